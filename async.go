@@ -0,0 +1,654 @@
+//
+// Copyright 2017 Radovan Vrždiak. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package clog
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"sync"
+	"sync/atomic"
+)
+
+// OverflowPolicy decides what happens with a message when queue of AsyncLogger
+// or storage queue (see WithQueue) is full.
+// QueueBlock favours completeness of the log (callers may wait), drop policies favour
+// liveness of callers. Dropped messages are counted, see Dropped.
+// Sync, Drain and Fatal methods always wait for free space.
+type OverflowPolicy int
+
+// Overflow policies.
+const (
+	QueueBlock      OverflowPolicy = iota // caller waits for free space in the queue
+	QueueDropNewest                       // new message is dropped, queued messages are kept
+	QueueDropOldest                       // the oldest queued message is dropped to make space for new one
+
+	QueueDrop = QueueDropNewest // alias of QueueDropNewest
+)
+
+// AsyncLogger is Logger writing messages by background goroutine so logging does not block callers.
+// Messages are composed (formatted, with caller info) by the caller
+// and written in the order they were logged, timestamp is the time of writing.
+//
+// Fatal methods and Sync wait until all queued messages are written.
+// Close must be called before exit to write queued messages.
+// Messages logged after Close are passed to closed inner logger synchronously.
+//
+// Asynchronous writing is available for loggers created by this package except for Tee,
+// calls of other loggers are forwarded synchronously.
+// Stack traces (see WithStacktrace) of queued messages are traces of the background goroutine.
+type AsyncLogger struct {
+	inner Logger
+	x     *logger // implementation of inner, nil if not available
+	q     *asyncQueue
+}
+
+// asyncQueue is shared by AsyncLogger and its children.
+type asyncQueue struct {
+	policy   OverflowPolicy
+	mu       sync.Mutex  // guards items, head, n and closed
+	items    []asyncItem // ring of n items starting at head
+	head, n  int
+	notEmpty *sync.Cond // signalled to writer goroutine
+	notFull  *sync.Cond // signalled to waiting senders
+	closed   bool
+	done     chan struct{} // closed when writer goroutine exits
+	dropped  uint64
+}
+
+// asyncItem is a queued message, raw write, storage write or flush request.
+type asyncItem struct {
+	x     *logger
+	lv    Level
+	e     entry
+	raw   []byte
+	w     *queueWriter // raw is written to w if set
+	flush chan struct{}
+}
+
+// NewAsync returns AsyncLogger writing messages of inner asynchronously.
+// queueSize is the maximum number of queued messages (at least 1), policy decides what happens if queue is full.
+func NewAsync(inner Logger, queueSize int, policy OverflowPolicy) *AsyncLogger {
+	if queueSize < 1 {
+		queueSize = 1
+	}
+
+	return &AsyncLogger{inner: inner, x: internal(inner), q: newAsyncQueue(queueSize, policy)}
+}
+
+// newAsyncQueue returns queue of given size, size must be at least 1.
+func newAsyncQueue(size int, policy OverflowPolicy) *asyncQueue {
+	q := &asyncQueue{
+		policy: policy,
+		items:  make([]asyncItem, size),
+		done:   make(chan struct{}),
+	}
+	q.notEmpty = sync.NewCond(&q.mu)
+	q.notFull = sync.NewCond(&q.mu)
+	go q.run()
+
+	return q
+}
+
+func (q *asyncQueue) run() {
+	defer close(q.done)
+
+	for {
+		q.mu.Lock()
+		for q.n == 0 && !q.closed {
+			q.notEmpty.Wait()
+		}
+		if q.n == 0 {
+			q.mu.Unlock()
+			return // closed and drained
+		}
+		it := q.items[q.head]
+		q.items[q.head] = asyncItem{}
+		q.head = (q.head + 1) % len(q.items)
+		q.n--
+		q.notFull.Broadcast()
+		q.mu.Unlock()
+
+		it.write()
+	}
+}
+
+func (it asyncItem) write() {
+	switch {
+	case it.flush != nil:
+		close(it.flush)
+	case it.w != nil:
+		it.w.put(it.lv, it.raw)
+	case it.raw != nil:
+		it.x.WriteRaw(it.lv, it.raw)
+	default:
+		if out := it.x.outputFor(it.lv); out != nil {
+			it.x.print(it.lv, out, it.e)
+		}
+	}
+}
+
+// send queues it, it is written synchronously if the queue is closed.
+// Full queue is handled by policy, items are written in the order they were queued.
+func (q *asyncQueue) send(it asyncItem) {
+	q.mu.Lock()
+	for q.n == len(q.items) && !q.closed {
+		switch {
+		case it.keep():
+			q.notFull.Wait()
+		case q.policy == QueueDropNewest:
+			atomic.AddUint64(&q.dropped, 1)
+			q.mu.Unlock()
+			return
+		case q.policy == QueueDropOldest && q.dropOldest():
+		default:
+			q.notFull.Wait() // QueueBlock or only kept items are queued
+		}
+	}
+	if q.closed {
+		q.mu.Unlock()
+		it.write()
+		return
+	}
+
+	q.items[(q.head+q.n)%len(q.items)] = it
+	q.n++
+	q.notEmpty.Signal()
+	q.mu.Unlock()
+}
+
+// keep reports whether it must not be dropped: flush requests and fatal storage writes.
+func (it asyncItem) keep() bool {
+	return it.flush != nil || it.w != nil && it.lv == DisabledLevel
+}
+
+// dropOldest removes the oldest queued item which can be dropped, q.mu must be held.
+// It reports whether an item was removed.
+func (q *asyncQueue) dropOldest() bool {
+	size := len(q.items)
+	for i := 0; i < q.n; i++ {
+		if q.items[(q.head+i)%size].keep() {
+			continue
+		}
+		for ; i < q.n-1; i++ {
+			q.items[(q.head+i)%size] = q.items[(q.head+i+1)%size]
+		}
+		q.items[(q.head+q.n-1)%size] = asyncItem{}
+		q.n--
+		atomic.AddUint64(&q.dropped, 1)
+		return true
+	}
+	return false
+}
+
+// drain waits until all queued items are written.
+func (q *asyncQueue) drain() {
+	flush := make(chan struct{})
+	q.send(asyncItem{flush: flush})
+	<-flush
+}
+
+// close writes all queued items and stops the background goroutine.
+// It reports whether q was already closed.
+func (q *asyncQueue) close() bool {
+	q.mu.Lock()
+	closed := q.closed
+	if !closed {
+		q.closed = true
+		q.notEmpty.Broadcast()
+		q.notFull.Broadcast()
+	}
+	q.mu.Unlock()
+
+	<-q.done
+	return closed
+}
+
+// Close writes all queued messages, stops the background goroutine and closes inner logger.
+// Next calls do nothing.
+func (a *AsyncLogger) Close() error {
+	if a.q.close() {
+		return nil
+	}
+	return a.inner.Close()
+}
+
+// Drain waits until all messages queued before the call are written.
+// Unlike Close the background goroutine keeps running, e.g. Drain before SetOutput
+// makes sure queued messages go to the old writer. Inner logger is not synced (see Sync).
+func (a *AsyncLogger) Drain() {
+	a.q.drain()
+}
+
+// Dropped returns number of messages dropped because queue was full (see OverflowPolicy).
+func (a *AsyncLogger) Dropped() uint64 {
+	return atomic.LoadUint64(&a.q.dropped)
+}
+
+// Dropped returns number of messages dropped by full queues of l (see OverflowPolicy):
+// queue of AsyncLogger and storage queue of its inner logger (see WithQueue),
+// sum of all loggers of Tee. It is 0 for other loggers.
+func Dropped(l Logger) uint64 {
+	switch x := l.(type) {
+	case *AsyncLogger:
+		return x.Dropped() + Dropped(x.inner)
+	case tee:
+		var n uint64
+		for _, l := range x {
+			n += Dropped(l)
+		}
+		return n
+	}
+	if x := internal(l); x != nil && x.storage != nil {
+		if q, ok := x.storage.get().(*queueWriter); ok {
+			return atomic.LoadUint64(&q.q.dropped)
+		}
+	}
+	return 0
+}
+
+// enabled reports whether messages of lv can be queued.
+func (a *AsyncLogger) enabled(lv Level) bool {
+	return a.x != nil && a.x.IsEnabled(lv)
+}
+
+func (a *AsyncLogger) enqueue(lv Level, e entry) {
+	a.q.send(asyncItem{x: a.x, lv: lv, e: e})
+}
+
+// child returns AsyncLogger of inner logger l sharing the queue with a.
+func (a *AsyncLogger) child(l Logger) Logger {
+	return &AsyncLogger{inner: l, x: internal(l), q: a.q}
+}
+
+// Fatal writes queued messages and the fatal message and exits.
+func (a *AsyncLogger) Fatal(msg ...interface{}) {
+	a.q.drain()
+	if a.x == nil || a.x.fatal == nil {
+		a.inner.Fatal(msg...)
+		return
+	}
+	a.x.exitFatal(a.x.compose(msg...))
+}
+
+// Fatalf writes queued messages and the formatted fatal message and exits.
+func (a *AsyncLogger) Fatalf(format string, msg ...interface{}) {
+	a.q.drain()
+	if a.x == nil || a.x.fatal == nil {
+		a.inner.Fatalf(format, msg...)
+		return
+	}
+	a.x.exitFatal(a.x.composef(format, msg...))
+}
+
+// Fatalw writes queued messages and the fatal message with fields and exits.
+func (a *AsyncLogger) Fatalw(msg string, fields ...Field) {
+	a.q.drain()
+	if a.x == nil || a.x.fatal == nil {
+		a.inner.Fatalw(msg, fields...)
+		return
+	}
+	a.x.exitFatal(a.x.composew(msg, fields))
+}
+
+// FatalContext writes queued messages and the fatal message with context fields and exits.
+func (a *AsyncLogger) FatalContext(ctx context.Context, msg ...interface{}) {
+	a.q.drain()
+	if a.x == nil || a.x.fatal == nil {
+		a.inner.FatalContext(ctx, msg...)
+		return
+	}
+	a.x.exitFatal(a.x.withContext(ctx, a.x.compose(msg...)))
+}
+
+// Fatalln writes queued messages and the fatal message with space separated operands and exits.
+func (a *AsyncLogger) Fatalln(msg ...interface{}) {
+	a.q.drain()
+	if a.x == nil || a.x.fatal == nil {
+		a.inner.Fatalln(msg...)
+		return
+	}
+	a.x.exitFatal(a.x.composeln(msg...))
+}
+
+func (a *AsyncLogger) Error(msg ...interface{}) {
+	if !a.enabled(ErrorLevel) {
+		a.inner.Error(msg...)
+		return
+	}
+	a.enqueue(ErrorLevel, a.x.compose(msg...))
+}
+
+func (a *AsyncLogger) Errorf(format string, msg ...interface{}) {
+	if !a.enabled(ErrorLevel) {
+		a.inner.Errorf(format, msg...)
+		return
+	}
+	a.enqueue(ErrorLevel, a.x.composef(format, msg...))
+}
+
+func (a *AsyncLogger) Errorw(msg string, fields ...Field) {
+	if !a.enabled(ErrorLevel) {
+		a.inner.Errorw(msg, fields...)
+		return
+	}
+	a.enqueue(ErrorLevel, a.x.composew(msg, fields))
+}
+
+func (a *AsyncLogger) Err(format string, args ...interface{}) error {
+	if !a.enabled(ErrorLevel) {
+		return a.inner.Err(format, args...)
+	}
+	err := fmt.Errorf(format, args...)
+	a.enqueue(ErrorLevel, a.x.composew(err.Error(), nil))
+	return err
+}
+
+func (a *AsyncLogger) ErrorErr(err error, msg ...interface{}) {
+	if !a.enabled(ErrorLevel) {
+		a.inner.ErrorErr(err, msg...)
+		return
+	}
+	e := a.x.compose(msg...)
+	e.fields = appendFieldList(e.fields, errorFields(err))
+	a.enqueue(ErrorLevel, e)
+}
+
+func (a *AsyncLogger) Errorln(msg ...interface{}) {
+	if !a.enabled(ErrorLevel) {
+		a.inner.Errorln(msg...)
+		return
+	}
+	a.enqueue(ErrorLevel, a.x.composeln(msg...))
+}
+
+func (a *AsyncLogger) ErrorContext(ctx context.Context, msg ...interface{}) {
+	if !a.enabled(ErrorLevel) {
+		a.inner.ErrorContext(ctx, msg...)
+		return
+	}
+	a.enqueue(ErrorLevel, a.x.withContext(ctx, a.x.compose(msg...)))
+}
+
+func (a *AsyncLogger) Warn(msg ...interface{}) {
+	if !a.enabled(WarnLevel) {
+		a.inner.Warn(msg...)
+		return
+	}
+	a.enqueue(WarnLevel, a.x.compose(msg...))
+}
+
+func (a *AsyncLogger) Warnf(format string, msg ...interface{}) {
+	if !a.enabled(WarnLevel) {
+		a.inner.Warnf(format, msg...)
+		return
+	}
+	a.enqueue(WarnLevel, a.x.composef(format, msg...))
+}
+
+func (a *AsyncLogger) Warnw(msg string, fields ...Field) {
+	if !a.enabled(WarnLevel) {
+		a.inner.Warnw(msg, fields...)
+		return
+	}
+	a.enqueue(WarnLevel, a.x.composew(msg, fields))
+}
+
+func (a *AsyncLogger) Warnln(msg ...interface{}) {
+	if !a.enabled(WarnLevel) {
+		a.inner.Warnln(msg...)
+		return
+	}
+	a.enqueue(WarnLevel, a.x.composeln(msg...))
+}
+
+func (a *AsyncLogger) WarnContext(ctx context.Context, msg ...interface{}) {
+	if !a.enabled(WarnLevel) {
+		a.inner.WarnContext(ctx, msg...)
+		return
+	}
+	a.enqueue(WarnLevel, a.x.withContext(ctx, a.x.compose(msg...)))
+}
+
+func (a *AsyncLogger) Info(msg ...interface{}) {
+	if !a.enabled(InfoLevel) {
+		a.inner.Info(msg...)
+		return
+	}
+	a.enqueue(InfoLevel, a.x.compose(msg...))
+}
+
+func (a *AsyncLogger) Infof(format string, msg ...interface{}) {
+	if !a.enabled(InfoLevel) {
+		a.inner.Infof(format, msg...)
+		return
+	}
+	a.enqueue(InfoLevel, a.x.composef(format, msg...))
+}
+
+func (a *AsyncLogger) Infow(msg string, fields ...Field) {
+	if !a.enabled(InfoLevel) {
+		a.inner.Infow(msg, fields...)
+		return
+	}
+	a.enqueue(InfoLevel, a.x.composew(msg, fields))
+}
+
+func (a *AsyncLogger) Infoln(msg ...interface{}) {
+	if !a.enabled(InfoLevel) {
+		a.inner.Infoln(msg...)
+		return
+	}
+	a.enqueue(InfoLevel, a.x.composeln(msg...))
+}
+
+func (a *AsyncLogger) InfoContext(ctx context.Context, msg ...interface{}) {
+	if !a.enabled(InfoLevel) {
+		a.inner.InfoContext(ctx, msg...)
+		return
+	}
+	a.enqueue(InfoLevel, a.x.withContext(ctx, a.x.compose(msg...)))
+}
+
+func (a *AsyncLogger) Debug(msg ...interface{}) {
+	if !a.enabled(DebugLevel) {
+		a.inner.Debug(msg...)
+		return
+	}
+	a.enqueue(DebugLevel, a.x.compose(msg...))
+}
+
+func (a *AsyncLogger) Debugf(format string, msg ...interface{}) {
+	if !a.enabled(DebugLevel) {
+		a.inner.Debugf(format, msg...)
+		return
+	}
+	a.enqueue(DebugLevel, a.x.composef(format, msg...))
+}
+
+func (a *AsyncLogger) Debugw(msg string, fields ...Field) {
+	if !a.enabled(DebugLevel) {
+		a.inner.Debugw(msg, fields...)
+		return
+	}
+	a.enqueue(DebugLevel, a.x.composew(msg, fields))
+}
+
+func (a *AsyncLogger) DebugBytes(prefix string, b []byte) {
+	if !a.enabled(DebugLevel) {
+		a.inner.DebugBytes(prefix, b)
+		return
+	}
+	a.enqueue(DebugLevel, a.x.composew(a.x.bytesMessage(prefix, b), nil))
+}
+
+func (a *AsyncLogger) Debugln(msg ...interface{}) {
+	if !a.enabled(DebugLevel) {
+		a.inner.Debugln(msg...)
+		return
+	}
+	a.enqueue(DebugLevel, a.x.composeln(msg...))
+}
+
+func (a *AsyncLogger) DebugContext(ctx context.Context, msg ...interface{}) {
+	if !a.enabled(DebugLevel) {
+		a.inner.DebugContext(ctx, msg...)
+		return
+	}
+	a.enqueue(DebugLevel, a.x.withContext(ctx, a.x.compose(msg...)))
+}
+
+func (a *AsyncLogger) Print(msg ...interface{}) {
+	lv, out := a.x.printOutput()
+	if out == nil {
+		a.inner.Print(msg...)
+		return
+	}
+	a.enqueue(lv, a.x.compose(msg...))
+}
+
+func (a *AsyncLogger) Printf(format string, msg ...interface{}) {
+	lv, out := a.x.printOutput()
+	if out == nil {
+		a.inner.Printf(format, msg...)
+		return
+	}
+	a.enqueue(lv, a.x.composef(format, msg...))
+}
+
+func (a *AsyncLogger) Println(msg ...interface{}) {
+	lv, out := a.x.printOutput()
+	if out == nil {
+		a.inner.Println(msg...)
+		return
+	}
+	a.enqueue(lv, a.x.composeln(msg...))
+}
+
+func (a *AsyncLogger) Log(level Level, msg ...interface{}) {
+	if !a.enabled(level) {
+		a.inner.Log(level, msg...)
+		return
+	}
+	a.enqueue(level, a.x.compose(msg...))
+}
+
+func (a *AsyncLogger) Logf(level Level, format string, msg ...interface{}) {
+	if !a.enabled(level) {
+		a.inner.Logf(level, format, msg...)
+		return
+	}
+	a.enqueue(level, a.x.composef(format, msg...))
+}
+
+// WriteRaw queues copy of p.
+func (a *AsyncLogger) WriteRaw(level Level, p []byte) {
+	if a.x == nil {
+		a.inner.WriteRaw(level, p)
+		return
+	}
+	a.q.send(asyncItem{x: a.x, lv: level, raw: append([]byte{}, p...)})
+}
+
+// StdLogger returns standard library logger of inner logger, it writes synchronously.
+func (a *AsyncLogger) StdLogger(level Level) *log.Logger {
+	return a.inner.StdLogger(level)
+}
+
+// ForLevel returns function writing messages at given level asynchronously.
+func (a *AsyncLogger) ForLevel(level Level) func(msg ...interface{}) {
+	sync := a.inner.ForLevel(level)
+	return func(msg ...interface{}) {
+		if !a.enabled(level) {
+			sync(msg...)
+			return
+		}
+		a.enqueue(level, a.x.compose(msg...))
+	}
+}
+
+// Named returns asynchronous child logger sharing the queue.
+func (a *AsyncLogger) Named(name string) Logger {
+	return a.child(a.inner.Named(name))
+}
+
+// WithPrefix returns asynchronous child logger sharing the queue.
+func (a *AsyncLogger) WithPrefix(prefix string) Logger {
+	return a.child(a.inner.WithPrefix(prefix))
+}
+
+// WithFields returns asynchronous child logger sharing the queue.
+func (a *AsyncLogger) WithFields(fields map[string]interface{}) Logger {
+	return a.child(a.inner.WithFields(fields))
+}
+
+// Clone returns asynchronous clone of inner logger sharing the queue.
+func (a *AsyncLogger) Clone() Logger {
+	return a.child(a.inner.Clone())
+}
+
+func (a *AsyncLogger) IsEnabled(level Level) bool {
+	return a.inner.IsEnabled(level)
+}
+
+func (a *AsyncLogger) ActiveLevels() []Level {
+	return a.inner.ActiveLevels()
+}
+
+// Counts returns counts of inner logger, queued messages are not counted until they are written.
+func (a *AsyncLogger) Counts() map[Level]uint64 {
+	return a.inner.Counts()
+}
+
+// SetFields replaces fields of inner logger, they are added to messages when they are logged.
+func (a *AsyncLogger) SetFields(fields ...Field) {
+	a.inner.SetFields(fields...)
+}
+
+func (a *AsyncLogger) ClearFields() {
+	a.inner.ClearFields()
+}
+
+// Sync writes queued messages and syncs inner logger.
+func (a *AsyncLogger) Sync() error {
+	a.q.drain()
+	return a.inner.Sync()
+}
+
+// SetOutput replaces storage writer of inner logger, queued messages may be written to the new writer.
+func (a *AsyncLogger) SetOutput(w io.Writer) {
+	a.inner.SetOutput(w)
+}
+
+// Truncate writes queued messages and truncates storage file of inner logger.
+func (a *AsyncLogger) Truncate() error {
+	a.q.drain()
+	return a.inner.Truncate()
+}
+
+func (a *AsyncLogger) SetLevel(level Level) error {
+	return a.inner.SetLevel(level)
+}
+
+func (a *AsyncLogger) EnableLevel(level Level) {
+	a.inner.EnableLevel(level)
+}
+
+func (a *AsyncLogger) DisableLevel(level Level) {
+	a.inner.DisableLevel(level)
+}
+
+// Reset writes queued messages (see Drain) and resets inner logger,
+// so queued messages are not written to restored storage writer.
+func (a *AsyncLogger) Reset() {
+	a.q.drain()
+	a.inner.Reset()
+}
+
+func (a *AsyncLogger) WithLevel(level Level) (restore func()) {
+	return a.inner.WithLevel(level)
+}
@@ -0,0 +1,235 @@
+package clog
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestAsyncOrder(t *testing.T) {
+	inner, buf := NewTestLogger(InfoLevel, WithFlags(0))
+	l := NewAsync(inner, 4, QueueBlock)
+
+	var expected string
+	for i := 0; i < 100; i++ {
+		l.Infof("msg %d", i)
+		expected += fmt.Sprintf("INFO:  msg %d\n", i)
+	}
+	l.Debug("hidden")
+	if err := l.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := buf.String(); got != expected {
+		t.Errorf("expected: %q, got: %q", expected, got)
+	}
+}
+
+func TestAsyncClose(t *testing.T) {
+	inner, buf := NewTestLogger(InfoLevel, WithFlags(0))
+	l := NewAsync(inner, 100, QueueBlock)
+
+	l.Named("db").Warnw("slow", Int("ms", 30))
+	l.WithPrefix("[x]").Error("failed")
+	l.WriteRaw(InfoLevel, []byte("raw\n"))
+	l.Close()
+	l.Close()
+	l.Info("after close") // inner is closed too
+
+	expected := "WARN:  db: slow ms=30\nERROR: [x] failed\nraw\n"
+	if got := buf.String(); got != expected {
+		t.Errorf("expected: %q, got: %q", expected, got)
+	}
+}
+
+// blockingWriter blocks writes until release is closed.
+type blockingWriter struct {
+	started chan struct{}
+	release chan struct{}
+	lines   []string
+}
+
+func (w *blockingWriter) Write(p []byte) (int, error) {
+	select {
+	case w.started <- struct{}{}:
+	default:
+	}
+	<-w.release
+	w.lines = append(w.lines, string(p))
+	return len(p), nil
+}
+
+func TestAsyncDrop(t *testing.T) {
+	w := &blockingWriter{started: make(chan struct{}), release: make(chan struct{})}
+	inner, err := NewWithLevel(w, InfoLevel, false, WithConsole(false))
+	if err != nil {
+		t.Fatal(err)
+	}
+	l := NewAsync(inner, 2, QueueDrop)
+
+	l.Info("first")
+	<-w.started // writer goroutine holds "first", queue is empty
+	for i := 0; i < 5; i++ {
+		l.Info("queued or dropped")
+	}
+	if n := l.Dropped(); n != 3 {
+		t.Errorf("expected 3 dropped messages, got %d", n)
+	}
+
+	close(w.release)
+	l.Close()
+	if n := len(w.lines); n != 3 {
+		t.Errorf("expected 3 written messages, got %d: %q", n, w.lines)
+	}
+}
+
+func TestAsyncOverflowPolicy(t *testing.T) {
+	tests := []struct {
+		policy  OverflowPolicy
+		dropped uint64
+		written []string
+	}{
+		{QueueDropNewest, 3, []string{"first", "m0", "m1"}},
+		{QueueDropOldest, 3, []string{"first", "m3", "m4"}},
+		{QueueBlock, 0, []string{"first", "m0", "m1", "m2", "m3", "m4"}},
+	}
+	for _, tt := range tests {
+		w := &blockingWriter{started: make(chan struct{}), release: make(chan struct{})}
+		inner, err := NewWithLevel(w, InfoLevel, false, WithConsole(false), WithFlags(0))
+		if err != nil {
+			t.Fatal(err)
+		}
+		l := NewAsync(inner, 2, tt.policy)
+
+		l.Info("first")
+		<-w.started // writer goroutine holds "first", queue is empty
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			for i := 0; i < 5; i++ {
+				l.Infof("m%d", i)
+			}
+		}()
+		if tt.policy == QueueBlock {
+			select {
+			case <-done:
+				t.Errorf("policy %d: expected blocked caller", tt.policy)
+			case <-time.After(20 * time.Millisecond):
+			}
+		} else {
+			<-done
+		}
+		if n := l.Dropped(); n != tt.dropped {
+			t.Errorf("policy %d: expected %d dropped messages, got %d", tt.policy, tt.dropped, n)
+		}
+
+		close(w.release)
+		<-done
+		l.Close()
+		var got []string
+		for _, s := range w.lines {
+			got = append(got, strings.TrimPrefix(strings.TrimSuffix(s, "\n"), "INFO:  "))
+		}
+		if strings.Join(got, ",") != strings.Join(tt.written, ",") {
+			t.Errorf("policy %d: expected: %q, got: %q", tt.policy, tt.written, got)
+		}
+	}
+}
+
+func TestDropOldestOrder(t *testing.T) {
+	w := &blockingWriter{started: make(chan struct{}), release: make(chan struct{})}
+	qw := newQueueWriter(w, 3, QueueDropOldest)
+
+	qw.Write([]byte("first"))
+	<-w.started // goroutine holds "first", queue is empty
+	qw.Write([]byte("e1"))
+	qw.WriteLevel(DisabledLevel, []byte("fatal")) // never dropped
+	qw.Write([]byte("e2"))
+	qw.Write([]byte("e3")) // drops e1
+	qw.Write([]byte("e4")) // drops e2, fatal stays first
+	close(w.release)
+	qw.Close()
+
+	expected := []string{"first", "fatal", "e3", "e4"}
+	if fmt.Sprint(w.lines) != fmt.Sprint(expected) {
+		t.Errorf("expected: %q, got: %q", expected, w.lines)
+	}
+	if n := atomic.LoadUint64(&qw.q.dropped); n != 2 {
+		t.Errorf("expected 2 dropped entries, got %d", n)
+	}
+}
+
+func TestDropOldestConcurrent(t *testing.T) {
+	inner, buf := NewTestLogger(InfoLevel, WithFlags(0))
+	l := NewAsync(inner, 4, QueueDropOldest)
+
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < 200; i++ {
+				l.Infof("%d %d", g, i)
+			}
+		}(g)
+	}
+	wg.Wait()
+	l.Close()
+
+	// messages of each goroutine are written in the order they were logged
+	last := map[int]int{}
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	for _, s := range lines {
+		var g, i int
+		if _, err := fmt.Sscanf(s, "INFO:  %d %d", &g, &i); err != nil {
+			t.Fatalf("unexpected line %q: %v", s, err)
+		}
+		if prev, ok := last[g]; ok && i <= prev {
+			t.Fatalf("goroutine %d: message %d written after %d", g, i, prev)
+		}
+		last[g] = i
+	}
+	if n := uint64(len(lines)) + l.Dropped(); n != 8*200 {
+		t.Errorf("expected written and dropped messages to sum up to %d, got %d", 8*200, n)
+	}
+}
+
+func TestAsyncFatal(t *testing.T) {
+	var buf *bytes.Buffer
+	var written string
+	inner, buf := NewTestLogger(InfoLevel, WithFlags(0), WithExitFunc(func(int) { written = buf.String() }))
+	l := NewAsync(inner, 10, QueueDrop)
+	defer l.Close()
+
+	l.Info("queued")
+	l.Fatal("bye")
+
+	if !strings.HasPrefix(written, "INFO:  queued\n") || !strings.Contains(written, "bye") {
+		t.Errorf("expected queued and fatal message before exit, got: %q", written)
+	}
+}
+
+func TestAsyncDrain(t *testing.T) {
+	inner, buf := NewTestLogger(InfoLevel, WithFlags(0))
+	l := NewAsync(inner, 1000, QueueBlock)
+	defer l.Close()
+
+	for i := 0; i < 1000; i++ {
+		l.Info("x")
+	}
+	l.Drain()
+	if n := strings.Count(buf.String(), "INFO:  x\n"); n != 1000 {
+		t.Errorf("expected 1000 messages written by Drain, got %d", n)
+	}
+
+	old := buf.String()
+	l.Info("after drain")
+	l.Drain()
+	if expected := old + "INFO:  after drain\n"; buf.String() != expected {
+		t.Errorf("expected message after drain, got: %q", strings.TrimPrefix(buf.String(), old))
+	}
+}
@@ -0,0 +1,59 @@
+//
+// Copyright 2017 Radovan Vrždiak. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package clog
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+)
+
+// BytesEncoding is the encoding of byte slices written by DebugBytes.
+type BytesEncoding int
+
+// Encodings of byte slices.
+const (
+	HexBytes    BytesEncoding = iota // lower case hexadecimal, e.g. 0a1bff
+	Base64Bytes                      // standard base64 with padding, e.g. Chv/
+)
+
+// WithBytesEncoding sets encoding of byte slices written by DebugBytes. Default is HexBytes.
+func WithBytesEncoding(enc BytesEncoding) Option {
+	return func(l *logger) {
+		l.bytesEnc = enc
+	}
+}
+
+// DebugBytes is for debug messages with binary data, e.g. protocol frames.
+// Message is prefix and b encoded according to WithBytesEncoding separated by space,
+// b is not formatted by fmt package.
+func (l *logger) DebugBytes(prefix string, b []byte) {
+	if l == nil || !l.levelEnabled(DebugLevel) || l.debug == nil {
+		return // Don't log at lower levels.
+	}
+	l.print(DebugLevel, l.debug, l.composew(l.bytesMessage(prefix, b), nil))
+}
+
+// bytesMessage returns prefix followed by encoded b.
+func (l *logger) bytesMessage(prefix string, b []byte) string {
+	n := hex.EncodedLen(len(b))
+	if l.bytesEnc == Base64Bytes {
+		n = base64.StdEncoding.EncodedLen(len(b))
+	}
+
+	msg := make([]byte, 0, len(prefix)+1+n)
+	msg = append(msg, prefix...)
+	if prefix != "" {
+		msg = append(msg, ' ')
+	}
+	start := len(msg)
+	msg = msg[:start+n]
+	if l.bytesEnc == Base64Bytes {
+		base64.StdEncoding.Encode(msg[start:], b)
+	} else {
+		hex.Encode(msg[start:], b)
+	}
+	return string(msg)
+}
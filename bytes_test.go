@@ -0,0 +1,42 @@
+package clog
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestDebugBytes(t *testing.T) {
+	frame := []byte{0x0a, 0x1b, 0xff, 0x00}
+	tests := []struct {
+		enc      BytesEncoding
+		prefix   string
+		expected string
+	}{
+		{HexBytes, "frame", "frame 0a1bff00"},
+		{HexBytes, "", "0a1bff00"},
+		{Base64Bytes, "frame", "frame Chv/AA=="},
+	}
+
+	for _, tt := range tests {
+		buf := &bytes.Buffer{}
+		l, err := New(buf, "debug", false, WithBytesEncoding(tt.enc))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		l.DebugBytes(tt.prefix, frame)
+		if !bytes.HasSuffix(buf.Bytes(), []byte(" "+tt.expected+"\n")) {
+			t.Errorf("expected suffix: %q, got: %q", tt.expected, buf.String())
+		}
+	}
+
+	buf := &bytes.Buffer{}
+	l, err := New(buf, "info", false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	l.DebugBytes("frame", frame)
+	if buf.Len() != 0 {
+		t.Errorf("expected nothing at info level, got: %q", buf.String())
+	}
+}
@@ -6,18 +6,35 @@
 // Package clog exports logging primitives that log to stderr and also to io.Writer (file, network, etc).
 // Usage is optimized for command line utilities and simple services.
 //
+// Operands of Debug, Info, Warn, Error and Fatal are formatted like by fmt.Sprint,
+// so spaces are added only between operands when neither is a string:
+//
+//	l.Info("count:", 5)   // count:5
+//	l.Info("a", "b")      // ab
+//	l.Info(1, 2)          // 1 2
+//
+// Debugln, Infoln, Warnln, Errorln, Fatalln and Println format operands like fmt.Sprintln
+// and always separate them by space:
+//
+//	l.Infoln("count:", 5) // count: 5
+//	l.Infoln("a", "b")    // a b
 package clog
 
 import (
+	"bytes"
+	"context"
 	"fmt"
 	"io"
-	"io/ioutil"
 	"log"
 	"os"
-	"path/filepath"
+	"regexp"
 	"runtime"
-	"sort"
+	"strconv"
 	"strings"
+	"sync/atomic"
+	"time"
+	"unicode"
+	"unicode/utf8"
 )
 
 // TODO
@@ -31,7 +48,8 @@ type Logger interface {
 	// Debugf writes a formated debug message to the log.
 	Debugf(fmt string, msg ...interface{})
 
-	// Info writes an info message to the log.
+	// Info writes an info message to the log. Operands are formatted like by fmt.Sprint,
+	// Info("count:", 5) writes "count:5", see Infoln for space separated operands.
 	Info(msg ...interface{})
 
 	// Infof writes a formated info message to the log.
@@ -49,24 +67,172 @@ type Logger interface {
 	// Errorf writes a formated error message to the log.
 	Errorf(fmt string, msg ...interface{})
 
-	// Error writes an error message to the log and aborts using os.Exit(1).
+	// Err writes a formated error message to the log and returns it as error (see fmt.Errorf).
+	Err(fmt string, args ...interface{}) error
+
+	// ErrorErr writes an error message with err and its causes to the log.
+	ErrorErr(err error, msg ...interface{})
+
+	// Error writes an error message to the log, flushes it (see Sync) and aborts using os.Exit(1).
 	Fatal(msg ...interface{})
 
-	// Error writes a formated error message to the log and aborts using os.Exit(1).
+	// Error writes a formated error message to the log, flushes it (see Sync) and aborts using os.Exit(1).
 	Fatalf(fmt string, msg ...interface{})
+
+	// Debugw writes a debug message with fields to the log.
+	Debugw(msg string, fields ...Field)
+
+	// Infow writes an info message with fields to the log.
+	Infow(msg string, fields ...Field)
+
+	// Warnw writes a warning message with fields to the log.
+	Warnw(msg string, fields ...Field)
+
+	// Errorw writes an error message with fields to the log.
+	Errorw(msg string, fields ...Field)
+
+	// Fatalw writes an error message with fields to the log and aborts using os.Exit(1).
+	Fatalw(msg string, fields ...Field)
+
+	// Print writes a message at print level (InfoLevel by default) to the log.
+	Print(msg ...interface{})
+
+	// Printf writes a formated message at print level (InfoLevel by default) to the log.
+	Printf(fmt string, msg ...interface{})
+
+	// Println writes a message at print level (InfoLevel by default) to the log, operands are space separated.
+	Println(msg ...interface{})
+
+	// Log writes a message at given level to the log, it never exits (see Fatal).
+	Log(level Level, msg ...interface{})
+
+	// Logf writes a formated message at given level to the log, it never exits (see Fatalf).
+	Logf(level Level, fmt string, msg ...interface{})
+
+	// DebugBytes writes a debug message with prefix and encoded b (see WithBytesEncoding) to the log.
+	DebugBytes(prefix string, b []byte)
+
+	// Debugln writes a debug message with space separated operands to the log.
+	Debugln(msg ...interface{})
+
+	// Infoln writes an info message with space separated operands to the log.
+	// Unlike Info (which formats like fmt.Sprint and adds spaces only between operands
+	// when neither is a string), Infoln("a", "b") writes "a b" like fmt.Sprintln does.
+	Infoln(msg ...interface{})
+
+	// Warnln writes a warning message with space separated operands to the log.
+	Warnln(msg ...interface{})
+
+	// Errorln writes an error message with space separated operands to the log.
+	Errorln(msg ...interface{})
+
+	// Fatalln writes an error message with space separated operands to the log, flushes it (see Sync)
+	// and aborts using os.Exit(1).
+	Fatalln(msg ...interface{})
+
+	// DebugContext writes a debug message with fields extracted from ctx to the log.
+	DebugContext(ctx context.Context, msg ...interface{})
+
+	// InfoContext writes an info message with fields extracted from ctx to the log.
+	InfoContext(ctx context.Context, msg ...interface{})
+
+	// WarnContext writes a warning message with fields extracted from ctx to the log.
+	WarnContext(ctx context.Context, msg ...interface{})
+
+	// ErrorContext writes an error message with fields extracted from ctx to the log.
+	ErrorContext(ctx context.Context, msg ...interface{})
+
+	// FatalContext writes an error message with fields extracted from ctx to the log
+	// and aborts using os.Exit(1).
+	FatalContext(ctx context.Context, msg ...interface{})
+
+	// StdLogger returns standard library logger writing to the log at given level.
+	StdLogger(level Level) *log.Logger
+
+	// ForLevel returns function writing messages formatted like by fmt.Sprint to the log at given level.
+	ForLevel(level Level) func(msg ...interface{})
+
+	// Named returns child logger which adds name to every message.
+	Named(name string) Logger
+
+	// WithPrefix returns child logger which prepends prefix to every message.
+	WithPrefix(prefix string) Logger
+
+	// WithFields returns child logger which adds fields to every message.
+	WithFields(fields map[string]interface{}) Logger
+
+	// SetFields replaces fields added to every message of the log.
+	SetFields(fields ...Field)
+
+	// ClearFields removes fields of SetFields.
+	ClearFields()
+
+	// WriteRaw writes pre-formatted bytes to the log at given level.
+	WriteRaw(level Level, p []byte)
+
+	// IsEnabled reports whether messages of given level are written to the log.
+	// It can be used to avoid expensive preparation of messages which would be discarded.
+	IsEnabled(level Level) bool
+
+	// ActiveLevels returns levels of messages written to the log, DisabledLevel stands for fatal messages.
+	ActiveLevels() []Level
+
+	// Counts returns numbers of messages written at each level, DisabledLevel stands for fatal messages.
+	Counts() map[Level]uint64
+
+	// Close syncs the log and closes its storage writers, next messages are discarded.
+	Close() error
+
+	// Sync writes pending messages and flushes buffered writers of the log.
+	Sync() error
+
+	// SetOutput replaces the storage writer of the log.
+	SetOutput(w io.Writer)
+
+	// Truncate truncates storage file of the log to zero size.
+	Truncate() error
+
+	// SetLevel changes level of the log, the level is shared by all Named loggers of the tree.
+	SetLevel(level Level) error
+
+	// WithLevel changes level of the log until restore is called.
+	WithLevel(level Level) (restore func())
+
+	// EnableLevel enables messages of given level regardless of level of the log.
+	EnableLevel(level Level)
+
+	// DisableLevel disables messages of given level regardless of level of the log.
+	DisableLevel(level Level)
+
+	// Reset restores level (removing overrides), storage writer and fields changed after creation of the log
+	// and zeroes Counts.
+	Reset()
+
+	// Clone returns independent copy of the log.
+	Clone() Logger
 }
 
 // Level represents the level of logging.
+// Levels are ordered by verbosity, DisabledLevel logs the least and DebugLevel the most.
+// Severity goes the opposite way: fatal messages are the most severe and they are written at every level.
+// FatalLevel names this end of the scale, it is the same level as DisabledLevel
+// (as threshold only fatal messages are written, as message level it stands for fatal messages).
+//
+// InvalidLevel is the zero value, it means the level was not specified or parsed.
+// It is returned by LevelFromString for unknown input (together with error),
+// its String is "" and it is rejected by Validate and by all constructors.
 type Level int
 
 // Levels of logging.
 const (
-	InvalidLevel Level = iota
-	DisabledLevel
+	InvalidLevel  Level = iota // not specified or unknown level
+	DisabledLevel              // only fatal messages are logged
 	ErrorLevel
 	WarnLevel
 	InfoLevel
 	DebugLevel
+
+	FatalLevel = DisabledLevel // alias of DisabledLevel, see Level
 )
 
 var logLevels = map[Level]string{
@@ -90,7 +256,7 @@ func (l Level) String() string {
 // Validate checks if log Level is valid.
 func (l Level) Validate() error {
 	if l == InvalidLevel {
-		return fmt.Errorf("log level is InvalidLevel, probably not specified")
+		return fmt.Errorf("log level is InvalidLevel, probably not specified; use one of: %s", levelsHint())
 	}
 
 	for lx := range logLevels {
@@ -99,229 +265,503 @@ func (l Level) Validate() error {
 		}
 	}
 
-	return fmt.Errorf("unknown log level %d", l)
+	return fmt.Errorf("unknown log level %d; use one of: %s", l, levelsHint())
 }
 
 // LevelFromString returns log level from given string.
-// Valid string parameters are: "disabled" | "error" | "warning" | "info" | "debug"
+// Valid string parameters are: "disabled" | "error" | "warning" | "info" | "debug",
+// "fatal" is accepted as alias of "disabled" (see FatalLevel).
+// InvalidLevel with error is returned for any other input
+// except for empty string which maps to InvalidLevel without error (see Level.String).
 func LevelFromString(s string) (Level, error) {
 	str := strings.TrimSpace(strings.ToLower(s))
-	hint := make([]Level, 0, len(logLevels))
 
 	for l, ls := range logLevels {
 		if ls == str {
 			return l, nil
 		}
-		if l != InvalidLevel {
-			hint = append(hint, l)
-		}
 	}
-
-	// hint should be sorted by level
-	sort.Slice(hint, func(i, j int) bool { return hint[i] < hint[j] })
-	hintStr := make([]string, 0, len(hint))
-	for _, l := range hint {
-		hintStr = append(hintStr, l.String())
+	if str == "fatal" {
+		return FatalLevel, nil
 	}
 
-	return InvalidLevel, fmt.Errorf("%q is not valid log level; use one of: %s", s, strings.Join(hintStr, " | "))
+	return InvalidLevel, fmt.Errorf("%q is not valid log level; use one of: %s", s, levelsHint())
+}
+
+// levelsHint returns valid levels for error messages, e.g. "disabled | error | warning | info | debug".
+func levelsHint() string {
+	return strings.Join(LevelStrings(), " | ")
 }
 
+// logger implements Logger. All methods are safe to call on nil *logger, they do nothing.
 type logger struct {
-	level   Level
+	level   *levelVar // shared with child loggers
 	w       io.Writer
 	verbose bool
+	console bool        // stdout/stderr mirror, see WithConsole
+	onError func(error) // see OnWriteError
 	// loggers for each log level
-	debug *log.Logger
-	info  *log.Logger
-	warn  *log.Logger
-	error *log.Logger
-	fatal *log.Logger
+	debug *output
+	info  *output
+	warn  *output
+	error *output
+	fatal *output
+	// optional message processing
+	dedup      *dedup
+	sampler    *sampler
+	now        func() time.Time
+	flags      int    // log.Ldate, log.Ltime, ... used for timestamp
+	timeFormat string // see WithTimeFormat
+	maxLen     int    // see WithMaxLength
+	escape     bool   // see WithEscapeControl
+	msgColumn  int    // see WithMessageColumn
+	separator  string // see WithSeparator
+	stackLevel Level  // see WithStacktrace
+	stackTrim  bool   // see WithStacktraceTrim
+	host       string // see WithHostname
+	showPID    bool   // see WithPID
+	showGoid   bool   // see WithGoroutineID
+	severity   bool   // see WithSeverity
+	startupLog bool   // see WithStartupLog
+	printLevel Level  // see WithPrintLevel
+	bytesEnc   BytesEncoding
+	counts     *levelCounts // see Counts
+	seq        *uint64      // last sequence number, nil - disabled, see WithSequence
+	redact     []*regexp.Regexp
+	format     Format
+	// consoleFormat overrides format of console mirror, nil - same as format
+	consoleFormat *Format
+	// noStoragePrefix omits level prefix in storage, see WithoutStorageLevelPrefix
+	noStoragePrefix bool
+	color           bool             // see WithColor
+	prefixes        map[Level]string // overrides of defaultPrefixes
+	extract         ContextExtractor
+	// caller formatting
+	callerPath CallerPath
+	callerFunc bool
+	callerSkip int          // see WithCallerSkip
+	callers    *callerCache // see WithCallerCache
+	// line termination
+	noNewline  bool
+	newlineSep *string
+	// fatal handling
+	storage *switchWriter // primary storage writer, see SetOutput
+	writers []io.Writer   // storage writers to be flushed by Sync
+	extra   []io.Writer   // see WithWriter
+	// batchSize and batchInterval enable batching of storage writes, see WithBatch
+	batchSize     int
+	batchInterval time.Duration
+	// queueSize and queuePolicy enable queueing of storage writes, see WithQueue
+	queueSize   int
+	queuePolicy OverflowPolicy
+	exit        func(code int)
+	exitCode    int
+	// fatalPolicy and fatalFn decide what happens after fatal message, see WithFatalPolicy
+	fatalPolicy FatalPolicy
+	fatalFn     func(msg string, code int)
+	closed      *int32 // 1 after Close, shared by children
+	// child logger data
+	name   string
+	prefix string     // see WithPrefix
+	fields []Field    // see WithFields
+	dyn    *dynFields // see SetFields
 }
 
 // New creates new Logger.
-func New(w io.Writer, level string, verbose bool) (Logger, error) {
-	l := &logger{w: w, verbose: verbose}
-
+// It returns nil Logger and error if level is not valid.
+// Optional behaviour can be enabled by opts, e.g. WithDedup.
+func New(w io.Writer, level string, verbose bool, opts ...Option) (Logger, error) {
 	lv, err := LevelFromString(level)
 	if err != nil {
-		return l, err
+		return nil, err
 	}
-	l.level = lv
 
-	flags := log.Ldate | log.Ltime
-	/*
-		if l.level == DebugLevel {
-			flags = log.Ldate | log.Ltime | log.Lshortfile
-		}
-	*/
+	return NewWithLevel(w, lv, verbose, opts...)
+}
 
-	storage := w
-	if storage == nil {
-		storage = ioutil.Discard
+// NewWithLevel creates new Logger like New with level given as Level.
+// It returns nil Logger and error if level is not valid (see Level.Validate).
+func NewWithLevel(w io.Writer, level Level, verbose bool, opts ...Option) (Logger, error) {
+	if err := level.Validate(); err != nil {
+		return nil, err
 	}
-	multiOut := io.MultiWriter(storage, os.Stdout)
-	multiErr := io.MultiWriter(storage, os.Stderr)
 
-	l.fatal = log.New(multiErr, "FATAL: ", flags)
-
-	if l.level == DisabledLevel {
-		return l, nil // leave debug, info, ... to be nil
+	l := newLogger(opts)
+	l.w = w
+	l.verbose = verbose
+	l.level = newLevelVar(level)
+
+	l.storage = newSwitchWriter(w, l.storageWrap())
+	l.writers = append([]io.Writer{l.storage}, l.extra...)
+	storage := l.reportErrors(l.storage)
+	if len(l.extra) > 0 {
+		ws := []io.Writer{storage}
+		for _, w := range l.extra {
+			ws = append(ws, l.reportErrors(w))
+		}
+		storage = newMultiWriter(ws...)
 	}
-
-	l.error = log.New(multiErr, "ERROR: ", flags)
-	if l.level == ErrorLevel {
-		return l, nil // leave debug, info, ... to be nil
+	multiOut := newMultiWriter(storage, os.Stdout)
+	multiErr := newMultiWriter(storage, os.Stderr)
+	mirror := l.mirrored()
+	if !l.console || mirror {
+		multiOut, multiErr = storage, storage
 	}
 
-	l.warn = log.New(multiErr, "WARN:  ", flags)
-	if l.level == WarnLevel {
-		return l, nil // leave debug, info to be nil
+	l.setup(func(lv Level) io.Writer {
+		switch {
+		case lv <= WarnLevel:
+			return multiErr // fatal, error, warn
+		case l.verbose:
+			return multiOut
+		}
+		return storage
+	})
+	if mirror {
+		l.setupMirrors()
 	}
+	l.logStartup(w)
 
-	l.info = log.New(storage, "INFO:  ", flags)
-	if l.verbose {
-		l.info = log.New(multiOut, "INFO:  ", flags)
-	}
-	if l.level == InfoLevel {
-		return l, nil // leave debug to be nil
+	return l.optimized(), nil
+}
+
+// NewCLI creates new Logger for command line tools following Unix conventions:
+// info and debug messages go to stdout, warnings, errors and fatal messages to stderr.
+// Optional w (e.g. file) receives all messages as well, nil w means no storage (see SetOutput).
+// It returns nil Logger and error if level is not valid (see Level.Validate).
+func NewCLI(w io.Writer, level Level, opts ...Option) (Logger, error) {
+	return NewWithLevel(w, level, true, opts...)
+}
+
+// newLogger returns logger with default settings modified by opts.
+func newLogger(opts []Option) *logger {
+	l := &logger{console: true, now: time.Now, flags: log.Ldate | log.Ltime, exit: os.Exit, exitCode: 1,
+		counts: &levelCounts{}, dyn: &dynFields{}, closed: new(int32)}
+	for _, opt := range opts {
+		opt(l)
 	}
+	return l
+}
+
+// setup creates outputs for all levels so the level can be changed by SetLevel.
+// dest returns writer for given level, DisabledLevel stands for fatal messages.
+// Only fatal output is created at DisabledLevel, the logger is optimized out (see optimized).
+func (l *logger) setup(dest func(Level) io.Writer) {
+	// timestamp and caller are formatted by logger itself (see appendStamp, caller and WithFlags),
+	// outputs only add prefix
 
-	l.debug = log.New(storage, "DEBUG: ", flags)
-	if l.verbose {
-		l.debug = log.New(multiOut, "DEBUG: ", flags)
+	l.fatal = l.newOutput(dest(DisabledLevel), DisabledLevel)
+
+	if l.level.get() == DisabledLevel {
+		return // leave debug, info, ... to be nil
 	}
 
-	return l, nil
+	l.error = l.newOutput(dest(ErrorLevel), ErrorLevel)
+	l.warn = l.newOutput(dest(WarnLevel), WarnLevel)
+	l.info = l.newOutput(dest(InfoLevel), InfoLevel)
+	l.debug = l.newOutput(dest(DebugLevel), DebugLevel)
 }
 
 // Fatal is for fatal error messages.
 func (l *logger) Fatal(msg ...interface{}) {
-	if l.fatal == nil {
+	if l == nil || l.fatal == nil {
 		return // Don't log at disabled level.
 	}
-	l.fatal.Fatal(l.compose(msg...))
+	l.exitFatal(l.compose(msg...))
 }
 
 // Fatalf is for formatted fatal error messages.
 func (l *logger) Fatalf(fmt string, msg ...interface{}) {
-	if l.fatal == nil {
+	if l == nil || l.fatal == nil {
 		return // Don't log at disabled level.
 	}
-	l.fatal.Fatal(l.composef(fmt, msg...))
+	l.exitFatal(l.composef(fmt, msg...))
 }
 
 // Error is for error messages.
 func (l *logger) Error(msg ...interface{}) {
-	if l.level < ErrorLevel || l.error == nil {
+	if l == nil || !l.levelEnabled(ErrorLevel) || l.error == nil {
 		return // Don't log at lower levels.
 	}
-	l.error.Print(l.compose(msg...))
+	l.print(ErrorLevel, l.error, l.compose(msg...))
 }
 
 // Errorf is for formatted error messages.
 func (l *logger) Errorf(fmt string, msg ...interface{}) {
-	if l.level < ErrorLevel || l.error == nil {
+	if l == nil || !l.levelEnabled(ErrorLevel) || l.error == nil {
 		return // Don't log at lower levels.
 	}
-	l.error.Print(l.composef(fmt, msg...))
+	l.print(ErrorLevel, l.error, l.composef(fmt, msg...))
 }
 
 // Warn is for warning messages.
 func (l *logger) Warn(msg ...interface{}) {
-	if l.level < WarnLevel || l.warn == nil {
+	if l == nil || !l.levelEnabled(WarnLevel) || l.warn == nil {
 		return // Don't log at lower levels.
 	}
-	l.warn.Print(l.compose(msg...))
+	l.print(WarnLevel, l.warn, l.compose(msg...))
 }
 
 // Warnf is for formatted warning messages.
 func (l *logger) Warnf(fmt string, msg ...interface{}) {
-	if l.level < WarnLevel || l.warn == nil {
+	if l == nil || !l.levelEnabled(WarnLevel) || l.warn == nil {
 		return // Don't log at lower levels.
 	}
-	l.warn.Print(l.composef(fmt, msg...))
+	l.print(WarnLevel, l.warn, l.composef(fmt, msg...))
 }
 
 // Info is for info messages.
 func (l *logger) Info(msg ...interface{}) {
-	if l.level < InfoLevel || l.info == nil {
+	if l == nil || !l.levelEnabled(InfoLevel) || l.info == nil {
 		return // Don't log at lower levels.
 	}
 	// l.info.Println(msg...)
-	l.info.Print(l.compose(msg...))
+	l.print(InfoLevel, l.info, l.compose(msg...))
 }
 
 // Infof is for formatted info messages.
 func (l *logger) Infof(fmt string, msg ...interface{}) {
-	if l.level < InfoLevel || l.info == nil {
+	if l == nil || !l.levelEnabled(InfoLevel) || l.info == nil {
 		return // Don't log at lower levels.
 	}
-	l.info.Print(l.composef(fmt, msg...))
+	l.print(InfoLevel, l.info, l.composef(fmt, msg...))
 }
 
 // Debug is for debug messages.
 func (l *logger) Debug(msg ...interface{}) {
-	if l.level < DebugLevel || l.debug == nil {
+	if l == nil || !l.levelEnabled(DebugLevel) || l.debug == nil {
 		return // Don't log at lower levels.
 	}
-	l.debug.Print(l.compose(msg...))
+	l.print(DebugLevel, l.debug, l.compose(msg...))
 }
 
 // Debugf is for formatted debug messages.
 func (l *logger) Debugf(fmt string, msg ...interface{}) {
-	if l.level < DebugLevel || l.debug == nil {
+	if l == nil || !l.levelEnabled(DebugLevel) || l.debug == nil {
 		return // Don't log at lower levels.
 	}
-	l.debug.Print(l.composef(fmt, msg...))
+	l.print(DebugLevel, l.debug, l.composef(fmt, msg...))
+}
+
+// IsEnabled reports whether messages of given level are written.
+func (l *logger) IsEnabled(level Level) bool {
+	return l != nil && level > DisabledLevel && l.levelEnabled(level) && l.outputFor(level) != nil
+}
+
+// outputFor returns output for given level, nil if level is not enabled.
+// Fatal messages are not covered, fatal output is used directly.
+func (l *logger) outputFor(lv Level) *output {
+	switch lv {
+	case ErrorLevel:
+		return l.error
+	case WarnLevel:
+		return l.warn
+	case InfoLevel:
+		return l.info
+	case DebugLevel:
+		return l.debug
+	}
+	return nil
+}
+
+// print writes composed message e to out.
+// It is the common write path of all levels except fatal.
+func (l *logger) print(lv Level, out *output, e entry) {
+	if l.sampler != nil && !l.sampler.allow(lv, l.now()) {
+		return
+	}
+	if l.dedup != nil {
+		summary, ok := l.dedup.check(lv, l.text(e), l.now())
+		if summary != "" {
+			l.write(out, entry{msg: summary, summary: true})
+		}
+		if !ok {
+			return
+		}
+	}
+	e.stack = l.stack(lv)
+	l.write(out, e)
+}
+
+// write encodes e and writes it to out unless l is closed.
+func (l *logger) write(out *output, e entry) {
+	if l.isClosed() {
+		return
+	}
+	l.emit(out, e)
+}
+
+// emit encodes e and writes it to out, it is used by Close to write pending entries.
+func (l *logger) emit(out *output, e entry) {
+	if !e.summary {
+		l.counts.count(out.level)
+	}
+	if l.seq != nil {
+		e.seq = atomic.AddUint64(l.seq, 1)
+	}
+	e.host = l.host
+	if l.severity {
+		e.severity = syslogSeverity(out.level)
+	}
+	e.fields = l.redactFields(e.fields)
+
+	b := getBuffer()
+	*b = l.encode(*b, out, &e)
+	out.Print(*b)
+	if out.mirror != nil {
+		*b = l.encode((*b)[:0], out.mirror, &e)
+		out.mirror.Print(*b)
+	}
+	putBuffer(b)
 }
 
 // caller adds inforation about source code file and line.
-// Runtime information is expensive so it is used only in DebugLevel.
+// Runtime information is expensive so it is used only in DebugLevel
+// unless log.Lshortfile or log.Llongfile flag is set (see WithFlags).
 func (l *logger) caller() string {
-	c := ""
-	if l.level == DebugLevel {
-		// see log/log.go of standard library
-		_, file, line, ok := runtime.Caller(3) // 3 - show file of code where logger is used
-		if !ok {
-			file = "???"
-			line = 0
+	if !l.callerEnabled() {
+		return ""
+	}
+
+	if l.callers != nil {
+		var pcs [1]uintptr
+		// 4 - skip runtime.Callers too, it is the same frame as runtime.Caller(3) below
+		if runtime.Callers(4+l.callerSkip, pcs[:]) == 1 {
+			return l.callers.get(l, pcs[0])
 		}
-		c = fmt.Sprintf("%s:%d ", file, line)
 	}
 
-	return c
+	// see log/log.go of standard library
+	pc, file, line, ok := runtime.Caller(3 + l.callerSkip) // 3 - show file of code where logger is used
+	if !ok {
+		file = "???"
+		line = 0
+	}
+	return l.formatCaller(pc, file, line, ok)
 }
 
-// compose prepares full log message. This time it ads caller info & PID if appropriate.
-func (l *logger) compose(msg ...interface{}) string {
-	output := []interface{}{l.pid(), l.caller()}
-	output = append(output, msg...)
+// callerEnabled reports whether messages contain caller info.
+func (l *logger) callerEnabled() bool {
+	return l.effectiveLevel() == DebugLevel || l.flags&(log.Lshortfile|log.Llongfile) != 0
+}
 
-	return fmt.Sprint(output...)
+// formatCaller returns caller info of code at pc, file and line, ok is false if they are not known.
+func (l *logger) formatCaller(pc uintptr, file string, line int, ok bool) string {
+	file = l.callerPathFlags().trim(file)
+	if l.callerFunc {
+		return fmt.Sprintf("%s (%s:%d)", funcName(pc, ok), file, line)
+	}
+	return fmt.Sprintf("%s:%d", file, line)
 }
 
-// composef prepares formatted full log message. This time it ads caller info & PID if appropriate.
-func (l *logger) composef(format string, msg ...interface{}) string {
-	return fmt.Sprint(l.pid(), l.caller(), fmt.Sprintf(format, msg...))
+// callerPathFlags returns caller path format given by flags, l.callerPath if none is set.
+func (l *logger) callerPathFlags() CallerPath {
+	switch {
+	case l.flags&log.Lshortfile != 0:
+		return ShortPath // like log package, Lshortfile overrides Llongfile
+	case l.flags&log.Llongfile != 0:
+		return FullPath
+	}
+	return l.callerPath
 }
 
-func (l *logger) pid() string {
-	p := ""
-	if l.level == DebugLevel {
-		p = fmt.Sprintf("[%d] ", os.Getpid())
+// funcName returns name of function containing pc without package path, e.g. "clog.New".
+func funcName(pc uintptr, ok bool) string {
+	fn := runtime.FuncForPC(pc)
+	if !ok || fn == nil {
+		return "???"
 	}
 
-	return p
+	name := fn.Name()
+	if i := strings.LastIndexByte(name, '/'); i >= 0 {
+		name = name[i+1:]
+	}
+	return name
 }
 
-// OpenFile helper function opens log file with options suitable for logging i.e. O_APPEND, etc.
-func OpenFile(fname string) (fd *os.File, err error) {
-	err = os.MkdirAll(filepath.Dir(fname), os.ModePerm)
-	if err != nil {
-		return
+// compose prepares full log message. This time it ads caller info & PID if appropriate and logger name.
+func (l *logger) compose(msg ...interface{}) entry {
+	return entry{prefix: l.prefix, pid: l.pid(), goid: l.goid(), caller: l.caller(), name: l.name, msg: l.message(fmt.Sprint(msg...)), fields: l.allFields()}
+}
+
+// composef prepares formatted full log message. This time it ads caller info & PID if appropriate and logger name.
+func (l *logger) composef(format string, msg ...interface{}) entry {
+	return entry{prefix: l.prefix, pid: l.pid(), goid: l.goid(), caller: l.caller(), name: l.name, msg: l.message(fmt.Sprintf(format, msg...)), fields: l.allFields()}
+}
+
+// composew prepares full log message with fields. This time it ads caller info & PID if appropriate and logger name.
+func (l *logger) composew(msg string, fields []Field) entry {
+	return entry{prefix: l.prefix, pid: l.pid(), goid: l.goid(), caller: l.caller(), name: l.name, msg: l.message(msg), fields: appendFieldList(l.allFields(), expandErrorFields(fields))}
+}
+
+// message returns s redacted (see WithRedaction), escaped (see WithEscapeControl)
+// and truncated (see WithMaxLength).
+func (l *logger) message(s string) string {
+	s = l.redactString(s)
+	if l.escape {
+		s = escapeControl(strings.TrimSuffix(s, "\n"))
+	}
+	if l.maxLen <= 0 || len(s) <= l.maxLen {
+		return s
 	}
 
-	fd, err = os.OpenFile(fname, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
-	return
+	n := l.maxLen
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n-- // don't split UTF-8 sequence
+	}
+	return s[:n] + fmt.Sprintf("…(truncated %d bytes)", len(s)-n)
+}
+
+// escapeControl returns s with control characters escaped like in Go string literals.
+func escapeControl(s string) string {
+	i := strings.IndexFunc(s, unicode.IsControl)
+	if i < 0 {
+		return s
+	}
+
+	var b strings.Builder
+	b.WriteString(s[:i])
+	for i < len(s) {
+		r, size := utf8.DecodeRuneInString(s[i:])
+		switch {
+		case r == '\n':
+			b.WriteString(`\n`)
+		case r == '\r':
+			b.WriteString(`\r`)
+		case r == '\t':
+			b.WriteString(`\t`)
+		case r < utf8.RuneSelf && unicode.IsControl(r):
+			fmt.Fprintf(&b, `\x%02x`, r)
+		case unicode.IsControl(r):
+			fmt.Fprintf(&b, `\u%04x`, r)
+		default:
+			b.WriteString(s[i : i+size])
+		}
+		i += size
+	}
+	return b.String()
+}
+
+// goid returns ID of the calling goroutine if it is shown with pid (see WithGoroutineID), 0 otherwise.
+func (l *logger) goid() uint64 {
+	if !l.showGoid || l.pid() == 0 {
+		return 0
+	}
+
+	var buf [64]byte
+	b := buf[:runtime.Stack(buf[:], false)]
+	b = bytes.TrimPrefix(b, []byte("goroutine "))
+	if i := bytes.IndexByte(b, ' '); i > 0 {
+		b = b[:i]
+	}
+	id, _ := strconv.ParseUint(string(b), 10, 64)
+	return id
+}
+
+// pid returns process ID shown in DebugLevel or if enabled by WithPID, 0 otherwise.
+func (l *logger) pid() int {
+	if l.showPID || l.effectiveLevel() == DebugLevel {
+		return os.Getpid()
+	}
+	return 0
 }
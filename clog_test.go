@@ -1,6 +1,18 @@
 package clog
 
-import "testing"
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
 
 // see logLevels map
 var testValidMap = map[Level]string{
@@ -24,14 +36,15 @@ func TestLevelFromString(t *testing.T) {
 		}
 	}
 
-	str := "nonsense"
-	l, err := LevelFromString(str)
-	if err == nil {
-		t.Errorf("processing: %q - expected error, got nil", str)
-	}
-	if l != InvalidLevel {
-		t.Errorf("processing: %q - expected %d (%q), got %d (%q)",
-			str, InvalidLevel, InvalidLevel.String(), l, l.String())
+	for _, str := range []string{"nonsense", "warn", "3", "info debug", "\x00"} {
+		l, err := LevelFromString(str)
+		if err == nil {
+			t.Errorf("processing: %q - expected error, got nil", str)
+		}
+		if l != InvalidLevel {
+			t.Errorf("processing: %q - expected %d (%q), got %d (%q)",
+				str, InvalidLevel, InvalidLevel.String(), l, l.String())
+		}
 	}
 }
 
@@ -65,3 +78,465 @@ func TestLevelValidate(t *testing.T) {
 		t.Error("invalid level 999 should return error, got nil")
 	}
 }
+
+func TestCallerPath(t *testing.T) {
+	_, file, _, _ := runtime.Caller(0)
+	tests := []struct {
+		path     CallerPath
+		expected string
+	}{
+		{ShortPath, " clog_test.go:"},
+		{PackagePath, " " + filepath.Base(filepath.Dir(file)) + "/clog_test.go:"},
+		{FullPath, " " + file + ":"},
+	}
+
+	for _, tt := range tests {
+		buf := &bytes.Buffer{}
+		l, err := New(buf, "debug", false, WithCallerPath(tt.path))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		_, _, line, _ := runtime.Caller(0)
+		l.Debug("caller")
+		expected := fmt.Sprintf("%s%d caller\n", tt.expected, line+1)
+		if !strings.HasSuffix(buf.String(), expected) {
+			t.Errorf("path %d: expected suffix %q, got: %q", tt.path, expected, buf.String())
+		}
+	}
+}
+
+func TestCallerFunc(t *testing.T) {
+	buf := &bytes.Buffer{}
+	l, err := New(buf, "debug", false, WithCallerPath(ShortPath), WithCallerFunc(true))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	_, _, line, _ := runtime.Caller(0)
+	l.Debug("caller")
+	expected := fmt.Sprintf(" clog.TestCallerFunc (clog_test.go:%d) caller\n", line+1)
+	if !strings.HasSuffix(buf.String(), expected) {
+		t.Errorf("expected suffix %q, got: %q", expected, buf.String())
+	}
+
+	// caller info is shown only at debug level
+	buf.Reset()
+	l, _ = New(buf, "info", false, WithCallerFunc(true))
+	l.Info("no caller")
+	if strings.Contains(buf.String(), "TestCallerFunc") {
+		t.Errorf("unexpected caller info: %q", buf.String())
+	}
+}
+
+// logWrapped is a wrapper of Logger like in helper libraries.
+func logWrapped(l Logger, msg string) {
+	l.Debug(msg)
+}
+
+func TestCallerSkip(t *testing.T) {
+	buf := &bytes.Buffer{}
+	l, err := New(buf, "debug", false, WithCallerPath(ShortPath), WithCallerSkip(1))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	_, _, line, _ := runtime.Caller(0)
+	logWrapped(l, "wrapped")
+	expected := fmt.Sprintf(" clog_test.go:%d wrapped\n", line+1)
+	if !strings.HasSuffix(buf.String(), expected) {
+		t.Errorf("expected suffix %q, got: %q", expected, buf.String())
+	}
+}
+
+func TestCallerCache(t *testing.T) {
+	buf := &bytes.Buffer{}
+	l, err := New(buf, "debug", false, WithCallerPath(ShortPath), WithCallerFunc(true), WithCallerCache(true))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	_, _, line, _ := runtime.Caller(0)
+	for i := 0; i < 3; i++ {
+		l.Debug("cached")
+	}
+	logWrapped(l.Named("sub"), "other site")
+
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	expected := fmt.Sprintf(" clog.TestCallerCache (clog_test.go:%d) cached", line+2)
+	for _, s := range lines[:3] {
+		if !strings.HasSuffix(s, expected) {
+			t.Errorf("expected suffix %q, got: %q", expected, s)
+		}
+	}
+	if !strings.Contains(lines[3], " clog.logWrapped (clog_test.go:") {
+		t.Errorf("expected caller of other call site, got: %q", lines[3])
+	}
+}
+
+func TestNilLogger(t *testing.T) {
+	var l Logger = (*logger)(nil)
+
+	defer func() {
+		if r := recover(); r != nil {
+			t.Errorf("nil logger should not panic: %v", r)
+		}
+	}()
+
+	ctx := context.Background()
+	l.Debug("x")
+	l.Debugf("%s", "x")
+	l.Info("x")
+	l.Infof("%s", "x")
+	l.Warn("x")
+	l.Warnf("%s", "x")
+	l.Error("x")
+	l.Errorf("%s", "x")
+	l.ErrorErr(errors.New("x"), "x")
+	l.Fatal("x")
+	l.Fatalf("%s", "x")
+	l.DebugContext(ctx, "x")
+	l.InfoContext(ctx, "x")
+	l.WarnContext(ctx, "x")
+	l.ErrorContext(ctx, "x")
+	l.FatalContext(ctx, "x")
+	l.StdLogger(InfoLevel).Print("x")
+	l.Named("x").Info("x")
+	l.WriteRaw(InfoLevel, []byte("x\n"))
+	if err := l.Sync(); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestNewInvalidLevel(t *testing.T) {
+	l, err := New(&bytes.Buffer{}, "nonsense", false)
+	if err == nil {
+		t.Error("expected error, got nil")
+	}
+	if l != nil {
+		t.Errorf("expected nil Logger on error, got %#v", l)
+	}
+}
+
+func TestCompose(t *testing.T) {
+	l := &logger{level: newLevelVar(InfoLevel), name: "db"}
+	tests := []struct {
+		got      string
+		expected string
+	}{
+		{l.text(l.compose("a", "b", 1, 2, "c")), "db: ab1 2c"},
+		{l.text(l.compose()), "db: "},
+		{l.text(l.composef("%s=%d", "n", 5)), "db: n=5"},
+		{l.text(l.composew("done", []Field{Int("n", 5)})), "db: done n=5"},
+	}
+	for _, tt := range tests {
+		if tt.got != tt.expected {
+			t.Errorf("expected: %q, got: %q", tt.expected, tt.got)
+		}
+	}
+}
+
+func TestComposeFastPath(t *testing.T) {
+	args := [][]interface{}{
+		{"a", "b"},
+		{1, 2},
+		{"count:", 5, 6, "x"},
+		{nil, errors.New("e"), 1.5},
+		{},
+	}
+
+	info := &logger{level: newLevelVar(InfoLevel)}
+	debug := &logger{level: newLevelVar(DebugLevel), callerPath: ShortPath}
+	pid := fmt.Sprintf("[%d] ", os.Getpid())
+	for _, a := range args {
+		expected := fmt.Sprint(a...)
+		if got := info.text(info.compose(a...)); got != expected {
+			t.Errorf("info %v: expected: %q, got: %q", a, expected, got)
+		}
+		// debug adds PID and caller
+		got := debug.text(debug.compose(a...))
+		if !strings.HasPrefix(got, pid+"testing.go:") || !strings.HasSuffix(got, " "+expected) {
+			t.Errorf("debug %v: expected %q with PID and caller, got: %q", a, expected, got)
+		}
+
+		expected = fmt.Sprintf("%v|%v", a...)
+		if got := info.text(info.composef("%v|%v", a...)); got != expected {
+			t.Errorf("info %v: expected: %q, got: %q", a, expected, got)
+		}
+		got = debug.text(debug.composef("%v|%v", a...))
+		if !strings.HasPrefix(got, pid+"testing.go:") || !strings.HasSuffix(got, " "+expected) {
+			t.Errorf("debug %v: expected %q with PID and caller, got: %q", a, expected, got)
+		}
+	}
+}
+
+func BenchmarkCompose(b *testing.B) {
+	l := &logger{level: newLevelVar(InfoLevel)}
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		l.compose("request handled", 200)
+	}
+}
+
+func BenchmarkComposef(b *testing.B) {
+	l := &logger{level: newLevelVar(InfoLevel)}
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		l.composef("request %s handled: %d", "/index", 200)
+	}
+}
+
+func BenchmarkComposeDebug(b *testing.B) {
+	l := &logger{level: newLevelVar(DebugLevel)}
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		l.compose("request handled", 200)
+	}
+}
+
+func BenchmarkComposefDebug(b *testing.B) {
+	l := &logger{level: newLevelVar(DebugLevel)}
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		l.composef("request %s handled: %d", "/index", 200)
+	}
+}
+
+func TestIsEnabled(t *testing.T) {
+	for base := DisabledLevel; base <= DebugLevel; base++ {
+		l, err := New(&bytes.Buffer{}, base.String(), false)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		for lv := InvalidLevel; lv <= DebugLevel+1; lv++ {
+			expected := lv > DisabledLevel && lv <= base
+			if got := l.IsEnabled(lv); got != expected {
+				t.Errorf("logger %q: IsEnabled(%d) expected: %v, got: %v", base, lv, expected, got)
+			}
+		}
+	}
+}
+
+func TestDisabledLogger(t *testing.T) {
+	buf := &bytes.Buffer{}
+	l, err := New(buf, "disabled", false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, ok := l.(disabled); !ok {
+		t.Errorf("expected optimized disabled logger, got %T", l)
+	}
+
+	l.Error("x")
+	l.Named("x").Info("x")
+	l.WriteRaw(ErrorLevel, []byte("x\n"))
+	l.StdLogger(ErrorLevel).Print("x")
+	if buf.Len() != 0 {
+		t.Errorf("expected no output, got: %q", buf.String())
+	}
+}
+
+// TestDisabledFatal runs itself in a subprocess because Fatal exits.
+func TestDisabledFatal(t *testing.T) {
+	if os.Getenv("CLOG_TEST_FATAL") == "1" {
+		l, _ := New(ioutil.Discard, "disabled", false)
+		l.Fatal("fatal message")
+		return // not reached
+	}
+
+	cmd := exec.Command(os.Args[0], "-test.run=^TestDisabledFatal$")
+	cmd.Env = append(os.Environ(), "CLOG_TEST_FATAL=1")
+	stderr := &bytes.Buffer{}
+	cmd.Stderr = stderr
+	err := cmd.Run()
+
+	e, ok := err.(*exec.ExitError)
+	if !ok || e.ExitCode() != 1 {
+		t.Fatalf("expected exit code 1, got: %v", err)
+	}
+	if !strings.Contains(stderr.String(), "FATAL: ") || !strings.Contains(stderr.String(), "fatal message") {
+		t.Errorf("expected fatal message on stderr, got: %q", stderr.String())
+	}
+}
+
+func TestNewWithLevelInvalid(t *testing.T) {
+	for _, lv := range []Level{InvalidLevel, Level(42), Level(-1)} {
+		l, err := NewWithLevel(&bytes.Buffer{}, lv, false)
+		if err == nil {
+			t.Errorf("level %d: expected error, got nil", lv)
+			continue
+		}
+		if l != nil {
+			t.Errorf("level %d: expected nil Logger, got %#v", lv, l)
+		}
+		if !strings.Contains(err.Error(), "disabled | error | warning | info | debug") {
+			t.Errorf("level %d: error should list valid levels, got: %v", lv, err)
+		}
+	}
+
+	// empty string parses to InvalidLevel which must be rejected as well
+	if _, err := New(&bytes.Buffer{}, "", false); err == nil {
+		t.Error("empty level: expected error, got nil")
+	}
+
+	l, err := NewWithLevel(&bytes.Buffer{}, WarnLevel, false)
+	if err != nil || !l.IsEnabled(WarnLevel) || l.IsEnabled(InfoLevel) {
+		t.Errorf("NewWithLevel(WarnLevel) unexpected result: %v, %v", l, err)
+	}
+}
+
+// captureConsole runs f with os.Stdout and os.Stderr redirected to files and returns their content.
+// Loggers must be created by f as console writers are bound on construction.
+func captureConsole(t *testing.T, f func()) (stdout, stderr string) {
+	t.Helper()
+
+	dir, err := ioutil.TempDir("", "clog")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	outFile, err := os.Create(filepath.Join(dir, "stdout"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	errFile, err := os.Create(filepath.Join(dir, "stderr"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer outFile.Close()
+	defer errFile.Close()
+
+	origOut, origErr := os.Stdout, os.Stderr
+	os.Stdout, os.Stderr = outFile, errFile
+	defer func() { os.Stdout, os.Stderr = origOut, origErr }()
+	f()
+
+	out, _ := ioutil.ReadFile(outFile.Name())
+	errOut, _ := ioutil.ReadFile(errFile.Name())
+	return string(out), string(errOut)
+}
+
+func TestNewCLI(t *testing.T) {
+	stdout, stderr := captureConsole(t, func() {
+		l, err := NewCLI(nil, InfoLevel, WithClock(testNow))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		l.Debug("hidden")
+		l.Info("info")
+		l.Warn("warn")
+		l.Error("error")
+	})
+
+	if expected := "INFO:  2017/03/09 14:05:07 info\n"; stdout != expected {
+		t.Errorf("stdout: expected: %q, got: %q", expected, stdout)
+	}
+	if expected := "WARN:  2017/03/09 14:05:07 warn\nERROR: 2017/03/09 14:05:07 error\n"; stderr != expected {
+		t.Errorf("stderr: expected: %q, got: %q", expected, stderr)
+	}
+}
+
+func TestNewCLIWithFile(t *testing.T) {
+	buf := &bytes.Buffer{}
+	stdout, stderr := captureConsole(t, func() {
+		l, err := NewCLI(buf, InfoLevel, WithClock(testNow))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		l.Info("info")
+		l.Error("error")
+	})
+
+	if !strings.Contains(stdout, "info") || strings.Contains(stdout, "error") {
+		t.Errorf("stdout: expected info only, got: %q", stdout)
+	}
+	if !strings.Contains(stderr, "error") || strings.Contains(stderr, "info") {
+		t.Errorf("stderr: expected error only, got: %q", stderr)
+	}
+	if expected := stdout + stderr; buf.String() != expected {
+		t.Errorf("file: expected: %q, got: %q", expected, buf.String())
+	}
+}
+
+func newBenchLogger(b testing.TB, level string) Logger {
+	l, err := New(ioutil.Discard, level, false)
+	if err != nil {
+		b.Fatalf("unexpected error: %v", err)
+	}
+	return l
+}
+
+func BenchmarkInfo(b *testing.B) {
+	l := newBenchLogger(b, "info")
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		l.Info("request handled", 200)
+	}
+}
+
+func BenchmarkInfof(b *testing.B) {
+	l := newBenchLogger(b, "info")
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		l.Infof("request %s handled: %d", "/index", 200)
+	}
+}
+
+func BenchmarkDebugEnabled(b *testing.B) {
+	l := newBenchLogger(b, "debug")
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		l.Debugf("request %s handled: %d", "/index", 200)
+	}
+}
+
+func BenchmarkDebugCaller(b *testing.B) {
+	l := newBenchLogger(b, "debug")
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		l.Debug("loop iteration")
+	}
+}
+
+func BenchmarkDebugCallerCached(b *testing.B) {
+	l, err := New(ioutil.Discard, "debug", false, WithCallerCache(true))
+	if err != nil {
+		b.Fatalf("unexpected error: %v", err)
+	}
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		l.Debug("loop iteration")
+	}
+}
+
+func BenchmarkDebugDisabled(b *testing.B) {
+	l := newBenchLogger(b, "info")
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		l.Debugf("request %s handled: %d", "/index", 200)
+	}
+}
+
+func BenchmarkDisabledLevel(b *testing.B) {
+	l := newBenchLogger(b, "disabled")
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		l.Errorf("request %s handled: %d", "/index", 200)
+	}
+}
+
+func TestInfoAllocs(t *testing.T) {
+	if testing.Short() {
+		t.Skip("allocation test")
+	}
+
+	l := newBenchLogger(t, "info")
+	allocs := testing.AllocsPerRun(100, func() {
+		l.Infof("request %s handled: %d", "/index", 200)
+	})
+	// message itself is allocated by fmt, encoding buffers are pooled
+	if allocs > 3 {
+		t.Errorf("Infof allocates %.0f times, expected at most 3", allocs)
+	}
+}
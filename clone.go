@@ -0,0 +1,91 @@
+//
+// Copyright 2017 Radovan Vrždiak. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package clog
+
+import (
+	"io"
+	"sync/atomic"
+)
+
+// Clone returns independent copy of l with the same configuration.
+// Unlike Named children, SetLevel and SetOutput of the clone do not affect l and vice versa.
+// The clone writes to the same writers until SetOutput is called.
+// State of WithDedup and WithSampler is not copied, the clone starts with empty counters.
+func (l *logger) Clone() Logger {
+	if l == nil {
+		return l
+	}
+
+	c := *l
+	c.level = newLevelVar(l.level.get())
+	if l.level != nil {
+		c.level.enabled = atomic.LoadUint32(&l.level.enabled)
+		c.level.disabled = atomic.LoadUint32(&l.level.disabled)
+	}
+	c.counts = &levelCounts{}
+	if l.seq != nil {
+		c.seq = new(uint64)
+	}
+	c.dyn = &dynFields{fields: l.dyn.get()}
+	if l.dedup != nil {
+		WithDedup(l.dedup.window)(&c)
+	}
+	if l.sampler != nil {
+		WithSampler(l.sampler.first, l.sampler.thereafter)(&c)
+	}
+
+	if l.storage != nil {
+		c.storage = newSwitchWriter(l.storage.unwrapped(), l.storage.wrap)
+		c.writers = make([]io.Writer, len(l.writers))
+		for i, w := range l.writers {
+			c.writers[i] = swapStorage(w, l.storage, c.storage)
+		}
+	}
+	c.fatal = l.fatal.clone(l.storage, c.storage)
+	c.error = l.error.clone(l.storage, c.storage)
+	c.warn = l.warn.clone(l.storage, c.storage)
+	c.info = l.info.clone(l.storage, c.storage)
+	c.debug = l.debug.clone(l.storage, c.storage)
+
+	return c.optimized()
+}
+
+// clone returns copy of o writing to storage instead of old storage.
+func (o *output) clone(old, storage *switchWriter) *output {
+	if o == nil {
+		return nil
+	}
+	return &output{
+		w:          swapStorage(o.w, old, storage),
+		level:      o.level,
+		prefix:     o.prefix,
+		newline:    o.newline,
+		newlineSep: o.newlineSep,
+		width:      o.width,
+		format:     o.format,
+		mirror:     o.mirror.clone(old, storage),
+	}
+}
+
+// swapStorage returns w with old storage replaced by storage.
+func swapStorage(w io.Writer, old, storage *switchWriter) io.Writer {
+	switch x := w.(type) {
+	case *switchWriter:
+		if x == old {
+			return storage
+		}
+	case multiWriter:
+		m := make(multiWriter, len(x))
+		for i, w := range x {
+			m[i] = swapStorage(w, old, storage)
+		}
+		return m
+	case errorWriter:
+		x.w = swapStorage(x.w, old, storage)
+		return x
+	}
+	return w
+}
@@ -0,0 +1,48 @@
+package clog
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestClone(t *testing.T) {
+	buf := &bytes.Buffer{}
+	l, err := New(buf, "warning", false, WithClock(testNow), OnWriteError(func(error) {}))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	c := l.Clone()
+	if err := c.SetLevel(InfoLevel); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if l.IsEnabled(InfoLevel) {
+		t.Error("SetLevel of clone changed level of original")
+	}
+
+	l.Info("original hidden")
+	c.Info("clone shared")
+
+	other := &bytes.Buffer{}
+	c.SetOutput(other)
+	l.Warn("original")
+	c.Warn("clone")
+
+	expected := "INFO:  2017/03/09 14:05:07 clone shared\nWARN:  2017/03/09 14:05:07 original\n"
+	if buf.String() != expected {
+		t.Errorf("original: expected: %q, got: %q", expected, buf.String())
+	}
+	if expected := "WARN:  2017/03/09 14:05:07 clone\n"; other.String() != expected {
+		t.Errorf("clone: expected: %q, got: %q", expected, other.String())
+	}
+}
+
+func TestCloneDisabled(t *testing.T) {
+	l, err := New(&bytes.Buffer{}, "disabled", false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, ok := l.Clone().(disabled); !ok {
+		t.Errorf("expected disabled clone, got: %T", l.Clone())
+	}
+}
@@ -0,0 +1,62 @@
+//
+// Copyright 2017 Radovan Vrždiak. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package clog
+
+import (
+	"errors"
+	"io"
+	"os"
+	"sync/atomic"
+)
+
+// ErrClosed is reported to OnWriteError callback for messages logged after Close.
+var ErrClosed = errors.New("log is closed")
+
+// Close syncs the log (see Sync) and closes storage writers implementing io.Closer
+// (w of New, sinks of NewMulti, writers of WithWriter), console is not closed.
+// It returns the first error encountered, next calls do nothing.
+//
+// Messages logged after Close are discarded and ErrClosed is reported to OnWriteError callback.
+// Fatal methods still write the message to stderr and exit. Children (see Named) and clones share closed state with l.
+func (l *logger) Close() error {
+	if l == nil || l.closed == nil || !atomic.CompareAndSwapInt32(l.closed, 0, 1) {
+		return nil
+	}
+
+	first := l.sync()
+	for _, w := range l.writers {
+		if err := closeWriter(w); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
+
+func closeWriter(w io.Writer) error {
+	if s, ok := w.(*switchWriter); ok {
+		w = s.get()
+	}
+	if w == os.Stdout || w == os.Stderr {
+		return nil
+	}
+
+	if c, ok := w.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
+
+// isClosed reports whether l is closed and reports ErrClosed if it is.
+func (l *logger) isClosed() bool {
+	if l.closed == nil || atomic.LoadInt32(l.closed) == 0 {
+		return false
+	}
+
+	if l.onError != nil {
+		l.onError(ErrClosed)
+	}
+	return true
+}
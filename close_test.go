@@ -0,0 +1,92 @@
+package clog
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestClose(t *testing.T) {
+	tmp, err := ioutil.TempDir("", "clog-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmp)
+
+	f, err := OpenFile(filepath.Join(tmp, "app.log"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var errs []error
+	l, err := New(f, "info", false, WithClock(testNow), WithConsole(false), OnWriteError(func(err error) {
+		errs = append(errs, err)
+	}))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	internal(l).exit = func(int) {}
+	child := l.Named("sub")
+
+	l.Info("before")
+	if err := l.Close(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := l.Close(); err != nil {
+		t.Errorf("second Close: unexpected error: %v", err)
+	}
+	if _, err := f.Write([]byte("x")); err == nil {
+		t.Error("expected file closed")
+	}
+
+	l.Info("after")
+	child.Errorf("child %d", 1)
+	l.WriteRaw(InfoLevel, []byte("raw\n"))
+	_, stderr := captureConsole(t, func() { l.Fatal("fatal") })
+	if expected := "FATAL: 2017/03/09 14:05:07 fatal\n"; stderr != expected {
+		t.Errorf("expected fatal message on stderr: %q, got: %q", expected, stderr)
+	}
+	if err := l.Sync(); err != ErrClosed {
+		t.Errorf("Sync expected: %v, got: %v", ErrClosed, err)
+	}
+
+	if len(errs) != 4 {
+		t.Fatalf("expected 4 errors, got: %v", errs)
+	}
+	for _, err := range errs {
+		if err != ErrClosed {
+			t.Errorf("expected: %v, got: %v", ErrClosed, err)
+		}
+	}
+
+	b, err := ioutil.ReadFile(f.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := "INFO:  2017/03/09 14:05:07 before\n"; string(b) != expected {
+		t.Errorf("expected: %q, got: %q", expected, b)
+	}
+}
+
+func TestCloseDedup(t *testing.T) {
+	var errs []error
+	l, buf := NewTestLogger(InfoLevel, WithFlags(0), WithDedup(time.Hour), OnWriteError(func(err error) {
+		errs = append(errs, err)
+	}))
+
+	for i := 0; i < 3; i++ {
+		l.Info("x")
+	}
+	if err := l.Close(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if expected := "INFO:  x\nINFO:  last message repeated 2 times\n"; buf.String() != expected {
+		t.Errorf("expected: %q, got: %q", expected, buf.String())
+	}
+	if len(errs) != 0 {
+		t.Errorf("expected no errors, got: %v", errs)
+	}
+}
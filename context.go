@@ -0,0 +1,101 @@
+//
+// Copyright 2017 Radovan Vrždiak. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package clog
+
+import "context"
+
+// ContextExtractor returns fields which should be attached to a message logged
+// by one of the *Context methods, e.g. trace ID carried by ctx.
+type ContextExtractor func(ctx context.Context) []Field
+
+// WithContextExtractor registers extractor used by *Context methods.
+// Default extractor is TraceIDExtractor, use it in custom extractors to keep trace IDs.
+func WithContextExtractor(extract ContextExtractor) Option {
+	return func(l *logger) {
+		l.extract = extract
+	}
+}
+
+// traceIDKey is context key of trace ID.
+type traceIDKey struct{}
+
+// ContextWithTraceID returns copy of ctx carrying trace ID id,
+// *Context methods of loggers with default extractor add it to messages as "trace_id" field.
+func ContextWithTraceID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, traceIDKey{}, id)
+}
+
+// TraceIDFromContext returns trace ID set by ContextWithTraceID, ok is false if ctx has none.
+func TraceIDFromContext(ctx context.Context) (id string, ok bool) {
+	id, ok = ctx.Value(traceIDKey{}).(string)
+	return id, ok
+}
+
+// TraceIDExtractor is ContextExtractor returning "trace_id" field of trace ID set by ContextWithTraceID.
+func TraceIDExtractor(ctx context.Context) []Field {
+	if id, ok := TraceIDFromContext(ctx); ok {
+		return []Field{Str("trace_id", id)}
+	}
+	return nil
+}
+
+// FatalContext is for fatal error messages with context fields.
+func (l *logger) FatalContext(ctx context.Context, msg ...interface{}) {
+	if l == nil || l.fatal == nil {
+		return // Don't log at disabled level.
+	}
+	l.exitFatal(l.withContext(ctx, l.compose(msg...)))
+}
+
+// ErrorContext is for error messages with context fields.
+func (l *logger) ErrorContext(ctx context.Context, msg ...interface{}) {
+	if l == nil || !l.levelEnabled(ErrorLevel) || l.error == nil {
+		return // Don't log at lower levels.
+	}
+	l.print(ErrorLevel, l.error, l.withContext(ctx, l.compose(msg...)))
+}
+
+// WarnContext is for warning messages with context fields.
+func (l *logger) WarnContext(ctx context.Context, msg ...interface{}) {
+	if l == nil || !l.levelEnabled(WarnLevel) || l.warn == nil {
+		return // Don't log at lower levels.
+	}
+	l.print(WarnLevel, l.warn, l.withContext(ctx, l.compose(msg...)))
+}
+
+// InfoContext is for info messages with context fields.
+func (l *logger) InfoContext(ctx context.Context, msg ...interface{}) {
+	if l == nil || !l.levelEnabled(InfoLevel) || l.info == nil {
+		return // Don't log at lower levels.
+	}
+	l.print(InfoLevel, l.info, l.withContext(ctx, l.compose(msg...)))
+}
+
+// DebugContext is for debug messages with context fields.
+func (l *logger) DebugContext(ctx context.Context, msg ...interface{}) {
+	if l == nil || !l.levelEnabled(DebugLevel) || l.debug == nil {
+		return // Don't log at lower levels.
+	}
+	l.print(DebugLevel, l.debug, l.withContext(ctx, l.compose(msg...)))
+}
+
+// withContext appends fields extracted from ctx to composed message e.
+func (l *logger) withContext(ctx context.Context, e entry) entry {
+	if ctx == nil {
+		return e
+	}
+	extract := l.extract
+	if extract == nil {
+		extract = TraceIDExtractor
+	}
+	fields := extract(ctx)
+	if len(fields) == 0 {
+		return e
+	}
+
+	e.fields = appendFieldList(e.fields, fields)
+	return e
+}
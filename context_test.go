@@ -0,0 +1,93 @@
+package clog
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+)
+
+type testCtxKey struct{}
+
+func testExtractor(ctx context.Context) []Field {
+	id, ok := ctx.Value(testCtxKey{}).(string)
+	if !ok {
+		return nil
+	}
+	return []Field{{Key: "trace", Value: id}}
+}
+
+func TestContextMethods(t *testing.T) {
+	buf := &bytes.Buffer{}
+	l, err := New(buf, "debug", false, WithContextExtractor(testExtractor))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	ctx := context.WithValue(context.Background(), testCtxKey{}, "abc 123")
+	l.InfoContext(ctx, "handled")
+	line := strings.TrimSpace(buf.String())
+	if !strings.HasSuffix(line, `handled trace="abc 123"`) {
+		t.Errorf("expected trace field, got: %q", line)
+	}
+
+	buf.Reset()
+	l.DebugContext(context.Background(), "no values")
+	if strings.Contains(buf.String(), "trace=") {
+		t.Errorf("unexpected trace field: %q", buf.String())
+	}
+
+	buf.Reset()
+	l.InfoContext(nil, "nil ctx")
+	if !strings.HasSuffix(buf.String(), " nil ctx\n") {
+		t.Errorf("nil ctx should log plain message, got: %q", buf.String())
+	}
+}
+
+func TestContextWithoutExtractor(t *testing.T) {
+	plain, ctxBuf := &bytes.Buffer{}, &bytes.Buffer{}
+	lp, _ := New(plain, "info", false)
+	lc, _ := New(ctxBuf, "info", false)
+
+	ctx := context.WithValue(context.Background(), testCtxKey{}, "abc")
+	lp.Info("same", 1)
+	lc.InfoContext(ctx, "same", 1)
+
+	// strip timestamps
+	p := plain.String()[strings.Index(plain.String(), "same"):]
+	c := ctxBuf.String()[strings.Index(ctxBuf.String(), "same"):]
+	if p != c {
+		t.Errorf("expected %q, got %q", p, c)
+	}
+}
+
+func TestTraceID(t *testing.T) {
+	l, buf := NewTestLogger(InfoLevel)
+	internal(l).flags = 0
+
+	ctx := ContextWithTraceID(context.Background(), "4bf92f35")
+	if id, ok := TraceIDFromContext(ctx); !ok || id != "4bf92f35" {
+		t.Errorf("expected trace ID %q, got: %q, %v", "4bf92f35", id, ok)
+	}
+	if _, ok := TraceIDFromContext(context.Background()); ok {
+		t.Error("expected no trace ID in background context")
+	}
+
+	l.InfoContext(ctx, "with trace")
+	l.Named("db").WarnContext(ctx, "slow")
+	l.InfoContext(context.Background(), "without trace")
+	expected := "INFO:  with trace trace_id=4bf92f35\n" +
+		"WARN:  db: slow trace_id=4bf92f35\n" +
+		"INFO:  without trace\n"
+	if buf.String() != expected {
+		t.Errorf("expected: %q, got: %q", expected, buf.String())
+	}
+
+	// custom extractor replaces the default one
+	l, buf = NewTestLogger(InfoLevel, WithContextExtractor(testExtractor))
+	internal(l).flags = 0
+	l.InfoContext(ctx, "custom")
+	if expected := "INFO:  custom\n"; buf.String() != expected {
+		t.Errorf("expected: %q, got: %q", expected, buf.String())
+	}
+}
@@ -0,0 +1,40 @@
+//
+// Copyright 2017 Radovan Vrždiak. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package clog
+
+import "sync/atomic"
+
+// levelCounts counts written messages per level (indexed by Level), it is shared by Named children.
+type levelCounts [DebugLevel + 1]uint64
+
+// count increments counter of written messages of lv.
+func (c *levelCounts) count(lv Level) {
+	if c != nil && lv > InvalidLevel && lv <= DebugLevel {
+		atomic.AddUint64(&c[lv], 1)
+	}
+}
+
+// reset sets all counters to zero.
+func (c *levelCounts) reset() {
+	for i := range c {
+		atomic.StoreUint64(&c[i], 0)
+	}
+}
+
+// Counts returns numbers of messages written at each level since construction,
+// DisabledLevel stands for fatal messages. Messages below the level of the logger,
+// sampled out or deduplicated are not counted, neither are WriteRaw calls.
+// Counts are shared by Named children (WithPrefix, WithFields), Clone starts from zero.
+func (l *logger) Counts() map[Level]uint64 {
+	counts := make(map[Level]uint64, DebugLevel)
+	for _, lv := range AllLevels() {
+		counts[lv] = 0
+		if l != nil && l.counts != nil {
+			counts[lv] = atomic.LoadUint64(&l.counts[lv])
+		}
+	}
+	return counts
+}
@@ -0,0 +1,55 @@
+package clog
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestCounts(t *testing.T) {
+	l, _ := NewTestLogger(InfoLevel)
+	internal(l).exit = func(int) {}
+
+	l.Debug("hidden")
+	l.Info("i")
+	l.Named("db").Infof("n=%d", 1)
+	l.WithPrefix("[x]").Warn("w")
+	l.Errorw("e", Int("n", 2))
+	l.Error("e")
+	l.Fatal("f")
+	l.WriteRaw(InfoLevel, []byte("raw\n"))
+
+	expected := map[Level]uint64{DisabledLevel: 1, ErrorLevel: 2, WarnLevel: 1, InfoLevel: 2, DebugLevel: 0}
+	if got := l.Counts(); fmt.Sprint(got) != fmt.Sprint(expected) {
+		t.Errorf("expected: %v, got: %v", expected, got)
+	}
+
+	c := l.Clone()
+	c.Info("clone")
+	if n := c.Counts()[InfoLevel]; n != 1 {
+		t.Errorf("clone expected 1 info message, got %d", n)
+	}
+	if n := l.Counts()[InfoLevel]; n != 2 {
+		t.Errorf("expected 2 info messages after clone, got %d", n)
+	}
+}
+
+func TestCountsDedup(t *testing.T) {
+	l, buf := NewTestLogger(InfoLevel, WithFlags(0), WithDedup(time.Hour))
+
+	l.Info("x")
+	l.Info("x")
+	l.Info("y") // summary of x is written first
+	l.Info("y")
+	if err := l.Sync(); err != nil { // summary of y
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := "INFO:  x\nINFO:  last message repeated 1 times\nINFO:  y\nINFO:  last message repeated 1 times\n"
+	if buf.String() != expected {
+		t.Errorf("expected: %q, got: %q", expected, buf.String())
+	}
+	if n := l.Counts()[InfoLevel]; n != 2 {
+		t.Errorf("expected 2 info messages without summaries, got %d", n)
+	}
+}
@@ -0,0 +1,115 @@
+//
+// Copyright 2017 Radovan Vrždiak. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package clog
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// WithDedup collapses identical consecutive messages of the same level.
+// When the composed message repeats within window it is suppressed and counted.
+// The count is reported as "last message repeated N times" line
+// when window elapses after the message was written, before the next different message
+// of that level, or on Sync and Close, whichever comes first.
+// Zero or negative window disables deduplication (default).
+func WithDedup(window time.Duration) Option {
+	return func(l *logger) {
+		if window <= 0 {
+			l.dedup = nil
+			return
+		}
+		l.dedup = &dedup{window: window, last: make(map[Level]dedupEntry), write: func(lv Level, s string) {
+			if out := l.outputFor(lv); out != nil {
+				l.write(out, entry{msg: s, summary: true})
+			}
+		}}
+	}
+}
+
+// dedup tracks the last written message per level.
+type dedup struct {
+	window time.Duration
+	mu     sync.Mutex
+	last   map[Level]dedupEntry
+	write  func(lv Level, summary string) // writes summaries of expired windows, nil - not written
+}
+
+type dedupEntry struct {
+	msg   string
+	count int         // suppressed repetitions
+	since time.Time   // when msg was written last time
+	timer *time.Timer // expires window of suppressed repetitions
+}
+
+// check reports whether composed message s of level lv should be written at time now.
+// Non-empty summary must be written before s.
+func (d *dedup) check(lv Level, s string, now time.Time) (summary string, write bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	e, seen := d.last[lv]
+	if seen && e.msg == s && now.Sub(e.since) < d.window {
+		e.count++
+		if e.timer == nil && d.write != nil {
+			since := e.since
+			e.timer = time.AfterFunc(d.window-now.Sub(since), func() { d.expire(lv, since) })
+		}
+		d.last[lv] = e
+		return "", false
+	}
+
+	if e.count > 0 {
+		summary = fmt.Sprintf("last message repeated %d times", e.count)
+	}
+	if e.timer != nil {
+		e.timer.Stop()
+	}
+	d.last[lv] = dedupEntry{msg: s, since: now}
+
+	return summary, true
+}
+
+// expire writes summary of lv when window of message written at since elapsed.
+// It does nothing if the message was replaced meanwhile.
+func (d *dedup) expire(lv Level, since time.Time) {
+	d.mu.Lock()
+	e := d.last[lv]
+	if !e.since.Equal(since) {
+		d.mu.Unlock()
+		return
+	}
+	count := e.count
+	e.count, e.timer = 0, nil
+	d.last[lv] = e
+	d.mu.Unlock()
+
+	if count > 0 {
+		d.write(lv, fmt.Sprintf("last message repeated %d times", count))
+	}
+}
+
+// flush returns summaries of suppressed messages per level and resets the counts.
+func (d *dedup) flush() map[Level]string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	summaries := make(map[Level]string)
+	for lv, e := range d.last {
+		if e.timer != nil {
+			e.timer.Stop()
+			e.timer = nil
+		}
+		if e.count > 0 {
+			summaries[lv] = fmt.Sprintf("last message repeated %d times", e.count)
+			e.count = 0
+		}
+		d.last[lv] = e
+	}
+
+	return summaries
+}
@@ -0,0 +1,108 @@
+package clog
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDedupBurst(t *testing.T) {
+	buf := &bytes.Buffer{}
+	l, err := New(buf, "info", false, WithDedup(time.Hour))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for i := 0; i < 100; i++ {
+		l.Info("dependency is down")
+	}
+	l.Info("dependency is up")
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	expected := []string{
+		"dependency is down",
+		"last message repeated 99 times",
+		"dependency is up",
+	}
+	if len(lines) != len(expected) {
+		t.Fatalf("expected %d lines, got %d:\n%s", len(expected), len(lines), buf.String())
+	}
+	for i, s := range expected {
+		if !strings.HasPrefix(lines[i], "INFO:  ") || !strings.HasSuffix(lines[i], s) {
+			t.Errorf("line %d expected: %q, got: %q", i, s, lines[i])
+		}
+	}
+}
+
+func TestDedupExpire(t *testing.T) {
+	conn := &fakeConn{}
+	l, err := New(conn, "info", false, WithConsole(false), WithFlags(0), WithDedup(20*time.Millisecond))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for i := 0; i < 3; i++ {
+		l.Info("x")
+	}
+	deadline := time.Now().Add(time.Second)
+	for len(conn.get()) < 2 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+
+	expected := []string{"INFO:  x\n", "INFO:  last message repeated 2 times\n"}
+	if p := conn.get(); strings.Join(p, "") != strings.Join(expected, "") {
+		t.Errorf("expected summary without next message: %q, got: %q", expected, p)
+	}
+	if err := l.Sync(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if p := conn.get(); len(p) != 2 {
+		t.Errorf("expected summary written once, got: %q", p)
+	}
+}
+
+func TestDedupDisabledByDefault(t *testing.T) {
+	buf := &bytes.Buffer{}
+	l, err := New(buf, "info", false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for i := 0; i < 3; i++ {
+		l.Info("same")
+	}
+	if n := strings.Count(buf.String(), "same"); n != 3 {
+		t.Errorf("expected 3 messages, got %d:\n%s", n, buf.String())
+	}
+}
+
+func TestDedupCheck(t *testing.T) {
+	d := &dedup{window: time.Second, last: make(map[Level]dedupEntry)}
+	t0 := time.Date(2017, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		level   Level
+		msg     string
+		at      time.Duration
+		summary string
+		write   bool
+	}{
+		{ErrorLevel, "x", 0, "", true},
+		{ErrorLevel, "x", 100 * time.Millisecond, "", false},
+		{ErrorLevel, "x", 200 * time.Millisecond, "", false},
+		{InfoLevel, "x", 300 * time.Millisecond, "", true}, // levels are independent
+		{ErrorLevel, "x", 1100 * time.Millisecond, "last message repeated 2 times", true},
+		{ErrorLevel, "x", 1200 * time.Millisecond, "", false},
+		{ErrorLevel, "y", 1300 * time.Millisecond, "last message repeated 1 times", true},
+		{ErrorLevel, "x", 1400 * time.Millisecond, "", true},
+	}
+
+	for i, tt := range tests {
+		summary, write := d.check(tt.level, tt.msg, t0.Add(tt.at))
+		if summary != tt.summary || write != tt.write {
+			t.Errorf("step %d: expected (%q, %v), got (%q, %v)",
+				i, tt.summary, tt.write, summary, write)
+		}
+	}
+}
@@ -0,0 +1,88 @@
+//
+// Copyright 2017 Radovan Vrždiak. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package clog
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"log"
+)
+
+// disabled is Logger at DisabledLevel. Only fatal messages are written,
+// all other methods have empty bodies so even the level check is elided.
+type disabled struct {
+	*logger
+}
+
+// optimized returns l or its optimized variant for l.level.
+func (l *logger) optimized() Logger {
+	if l.level.get() == DisabledLevel {
+		return disabled{l}
+	}
+	return l
+}
+
+func (disabled) Debug(msg ...interface{})                             {}
+func (disabled) Debugf(fmt string, msg ...interface{})                {}
+func (disabled) Info(msg ...interface{})                              {}
+func (disabled) Infof(fmt string, msg ...interface{})                 {}
+func (disabled) Warn(msg ...interface{})                              {}
+func (disabled) Warnf(fmt string, msg ...interface{})                 {}
+func (disabled) Error(msg ...interface{})                             {}
+func (disabled) Errorf(fmt string, msg ...interface{})                {}
+func (disabled) ErrorErr(err error, msg ...interface{})               {}
+func (disabled) Debugw(msg string, fields ...Field)                   {}
+func (disabled) Infow(msg string, fields ...Field)                    {}
+func (disabled) Warnw(msg string, fields ...Field)                    {}
+func (disabled) Errorw(msg string, fields ...Field)                   {}
+func (disabled) Print(msg ...interface{})                             {}
+func (disabled) Printf(fmt string, msg ...interface{})                {}
+func (disabled) Println(msg ...interface{})                           {}
+func (disabled) Debugln(msg ...interface{})                           {}
+func (disabled) DebugBytes(prefix string, b []byte)                   {}
+func (disabled) Infoln(msg ...interface{})                            {}
+func (disabled) Warnln(msg ...interface{})                            {}
+func (disabled) Errorln(msg ...interface{})                           {}
+func (disabled) DebugContext(ctx context.Context, msg ...interface{}) {}
+func (disabled) InfoContext(ctx context.Context, msg ...interface{})  {}
+func (disabled) WarnContext(ctx context.Context, msg ...interface{})  {}
+func (disabled) ErrorContext(ctx context.Context, msg ...interface{}) {}
+func (disabled) WriteRaw(level Level, p []byte)                       {}
+func (disabled) IsEnabled(level Level) bool                           { return false }
+func (disabled) StdLogger(level Level) *log.Logger                    { return log.New(ioutil.Discard, "", 0) }
+func (disabled) ForLevel(level Level) func(msg ...interface{})        { return func(...interface{}) {} }
+
+// Named returns disabled child logger.
+func (d disabled) Named(name string) Logger {
+	return disabled{d.logger.Named(name).(*logger)}
+}
+
+// SetLevel returns error, level of disabled logger can't be changed.
+func (disabled) SetLevel(level Level) error {
+	return fmt.Errorf("level of logger created at %s level can't be changed", DisabledLevel)
+}
+
+// EnableLevel does nothing, level of disabled logger can't be changed.
+func (disabled) EnableLevel(level Level) {}
+
+// DisableLevel does nothing, only fatal messages are written anyway.
+func (disabled) DisableLevel(level Level) {}
+
+// WithLevel does nothing, level of disabled logger can't be changed.
+func (disabled) WithLevel(level Level) (restore func()) {
+	return func() {}
+}
+
+// WithPrefix returns disabled child logger.
+func (d disabled) WithPrefix(prefix string) Logger {
+	return disabled{d.logger.WithPrefix(prefix).(*logger)}
+}
+
+// WithFields returns disabled child logger.
+func (d disabled) WithFields(fields map[string]interface{}) Logger {
+	return disabled{d.logger.WithFields(fields).(*logger)}
+}
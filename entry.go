@@ -0,0 +1,476 @@
+//
+// Copyright 2017 Radovan Vrždiak. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package clog
+
+import (
+	"fmt"
+	"log"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// Format is the encoding of log entries.
+type Format int
+
+// Formats of log entries.
+const (
+	// TextFormat is the default human readable format:
+	//	INFO:  2017/03/09 14:05:07 db: connected host=localhost
+	TextFormat Format = iota
+
+	// JSONFormat writes every entry as one JSON object, level prefix is not written
+	// and prefix of WithPrefix is written as "prefix" key:
+	//	{"time":"2017-03-09T14:05:07Z","level":"info","name":"db","msg":"connected","host":"localhost"}
+	// Keys pid and caller are added in DebugLevel the same way as in text.
+	JSONFormat
+
+	// LogfmtFormat writes every entry as space separated key=value pairs,
+	// keys are the same as in JSONFormat except for time which is ts:
+	//	ts=2017-03-09T14:05:07Z level=info name=db msg=connected host=localhost
+	// Values containing spaces, quotes or '=' are quoted.
+	LogfmtFormat
+)
+
+// WithFormat sets format of log entries. Default is TextFormat.
+func WithFormat(f Format) Option {
+	return func(l *logger) {
+		l.format = f
+	}
+}
+
+// WithConsoleFormat sets format of entries mirrored to console (stdout, stderr),
+// e.g. human readable TextFormat on console and JSONFormat (see WithFormat) in storage writer.
+// Entries are encoded twice if the formats differ. Default is the format of WithFormat.
+func WithConsoleFormat(f Format) Option {
+	return func(l *logger) {
+		l.consoleFormat = &f
+	}
+}
+
+// entry is a composed log message. It is encoded by logger when it is written.
+type entry struct {
+	host     string // "" - not shown, see WithHostname
+	prefix   string // "" - not shown
+	pid      int    // 0 - not shown
+	goid     uint64 // 0 - not shown, see WithGoroutineID
+	caller   string // "" - not shown
+	name     string
+	msg      string
+	fields   []Field
+	stack    string // "" - not shown, see WithStacktrace
+	seq      uint64 // 0 - not shown, see WithSequence
+	severity int    // syslog severity, 0 - not shown, see WithSeverity
+	summary  bool   // summary of WithDedup, not counted
+}
+
+// encode appends e encoded for out to b including timestamp.
+func (l *logger) encode(b []byte, out *output, e *entry) []byte {
+	switch out.format {
+	case JSONFormat:
+		return e.appendJSON(b, out.level, l.jsonTime(), l.jsonLayout())
+	case LogfmtFormat:
+		return e.appendLogfmt(b, out.level, l.jsonTime(), l.jsonLayout())
+	}
+
+	start := len(b)
+	b = l.appendStamp(b)
+	b = e.appendHeader(b, l.sep())
+	if l.msgColumn > 0 {
+		// column is counted from the line start including level prefix written by out
+		for n := out.width + utf8.RuneCount(b[start:]); n < l.msgColumn; n++ {
+			b = append(b, ' ')
+		}
+	}
+	return e.appendMessage(b, l.timeLayout())
+}
+
+// WithMessageColumn aligns messages in TextFormat so they start at column n (counted from 0)
+// after level prefix, timestamp, name and other decorations, e.g. for human scanning of console.
+// Messages with longer decorations are not aligned. It is disabled by default (n = 0).
+func WithMessageColumn(n int) Option {
+	return func(l *logger) {
+		l.msgColumn = n
+	}
+}
+
+// WithSeparator sets separator of segments preceding message in TextFormat, i.e. timestamp,
+// sequence number, host, prefix, pid, caller and name, e.g. " | " writes
+// "INFO:  2017/03/09 14:05:07 | [1234] | main.go:10 | db: | connected". Level prefix is not affected.
+// Default (and empty sep) is single space.
+func WithSeparator(sep string) Option {
+	return func(l *logger) {
+		l.separator = sep
+	}
+}
+
+// sep returns separator of text segments, see WithSeparator.
+func (l *logger) sep() string {
+	if l.separator == "" {
+		return " "
+	}
+	return l.separator
+}
+
+// Compose returns entry of given level as it is written in TextFormat with default level prefixes
+// but without timestamp and trailing newline, e.g. "INFO:  [42] main.go:10 connected".
+// Operands are formatted like by fmt.Sprint, pid 0 and empty caller are not written.
+// DisabledLevel stands for fatal messages. It is intended for WriteRaw, custom sinks and golden tests.
+func Compose(level Level, pid int, caller string, msg ...interface{}) string {
+	e := entry{pid: pid, caller: caller, msg: fmt.Sprint(msg...)}
+	b := append([]byte(defaultPrefixes[level]), e.appendText(nil, time.RFC3339, " ")...)
+	return string(b)
+}
+
+// text returns text representation of e without timestamp.
+func (l *logger) text(e entry) string {
+	return string(e.appendText(nil, l.timeLayout(), l.sep()))
+}
+
+// appendText appends e to b as "#seq host prefix [pid] caller name: msg key=value ...", layout formats time fields
+// and sep separates segments preceding message.
+func (e *entry) appendText(b []byte, layout, sep string) []byte {
+	b = e.appendHeader(b, sep)
+	return e.appendMessage(b, layout)
+}
+
+// appendHeader appends text decorations preceding message of e to b, each one followed by sep.
+func (e *entry) appendHeader(b []byte, sep string) []byte {
+	if e.seq != 0 {
+		b = append(b, '#')
+		b = strconv.AppendUint(b, e.seq, 10)
+		b = append(b, sep...)
+	}
+	if e.host != "" {
+		b = append(b, e.host...)
+		b = append(b, sep...)
+	}
+	if e.prefix != "" {
+		b = append(b, e.prefix...)
+		b = append(b, sep...)
+	}
+	if e.pid != 0 {
+		b = append(b, '[')
+		b = strconv.AppendInt(b, int64(e.pid), 10)
+		if e.goid != 0 {
+			b = append(b, ':')
+			b = strconv.AppendUint(b, e.goid, 10)
+		}
+		b = append(b, ']')
+		b = append(b, sep...)
+	}
+	if e.caller != "" {
+		b = append(b, e.caller...)
+		b = append(b, sep...)
+	}
+	if e.name != "" {
+		b = append(b, e.name...)
+		b = append(b, ':')
+		b = append(b, sep...)
+	}
+	return b
+}
+
+// appendMessage appends message, fields and stack of e to b, layout formats time fields.
+func (e *entry) appendMessage(b []byte, layout string) []byte {
+	b = append(b, e.msg...)
+	b = appendFields(b, e.fields, layout)
+
+	return appendStack(b, e.stack)
+}
+
+// appendStack appends stack to b as block of lines indented by tab.
+func appendStack(b []byte, stack string) []byte {
+	if stack == "" {
+		return b
+	}
+
+	for _, line := range strings.Split(strings.TrimSuffix(stack, "\n"), "\n") {
+		b = append(b, "\n\t"...)
+		b = append(b, line...)
+	}
+	return b
+}
+
+// appendJSON appends e to b as JSON object, empty ts is omitted. Layout formats time fields.
+func (e *entry) appendJSON(b []byte, lv Level, ts, layout string) []byte {
+	b = append(b, '{')
+	if ts != "" {
+		b = append(b, `"time":`...)
+		b = appendJSONString(b, ts)
+		b = append(b, ',')
+	}
+	b = append(b, `"level":`...)
+	b = appendJSONString(b, jsonLevel(lv))
+	if e.severity != 0 {
+		b = append(b, `,"severity":`...)
+		b = strconv.AppendInt(b, int64(e.severity), 10)
+	}
+	if e.seq != 0 {
+		b = append(b, `,"seq":`...)
+		b = strconv.AppendUint(b, e.seq, 10)
+	}
+	if e.host != "" {
+		b = append(b, `,"host":`...)
+		b = appendJSONString(b, e.host)
+	}
+	if e.prefix != "" {
+		b = append(b, `,"prefix":`...)
+		b = appendJSONString(b, e.prefix)
+	}
+	if e.name != "" {
+		b = append(b, `,"name":`...)
+		b = appendJSONString(b, e.name)
+	}
+	if e.pid != 0 {
+		b = append(b, `,"pid":`...)
+		b = strconv.AppendInt(b, int64(e.pid), 10)
+	}
+	if e.goid != 0 {
+		b = append(b, `,"goid":`...)
+		b = strconv.AppendUint(b, e.goid, 10)
+	}
+	if e.caller != "" {
+		b = append(b, `,"caller":`...)
+		b = appendJSONString(b, e.caller)
+	}
+	b = append(b, `,"msg":`...)
+	b = appendJSONString(b, e.msg)
+	for _, f := range e.fields {
+		b = append(b, ',')
+		b = appendJSONString(b, f.Key)
+		b = append(b, ':')
+		b = appendJSONValue(b, f.Value, layout)
+	}
+	if e.stack != "" {
+		b = append(b, `,"stack":`...)
+		b = appendJSONString(b, e.stack)
+	}
+
+	return append(b, '}')
+}
+
+// appendLogfmt appends e to b as key=value pairs, empty ts is omitted. Layout formats time fields.
+func (e *entry) appendLogfmt(b []byte, lv Level, ts, layout string) []byte {
+	if ts != "" {
+		b = append(b, "ts="...)
+		b = appendValue(b, ts)
+		b = append(b, ' ')
+	}
+	b = append(b, "level="...)
+	b = append(b, jsonLevel(lv)...)
+	if e.severity != 0 {
+		b = append(b, " severity="...)
+		b = strconv.AppendInt(b, int64(e.severity), 10)
+	}
+	if e.seq != 0 {
+		b = append(b, " seq="...)
+		b = strconv.AppendUint(b, e.seq, 10)
+	}
+	if e.host != "" {
+		b = append(b, " host="...)
+		b = appendValue(b, e.host)
+	}
+	if e.prefix != "" {
+		b = append(b, " prefix="...)
+		b = appendValue(b, e.prefix)
+	}
+	if e.name != "" {
+		b = append(b, " name="...)
+		b = appendValue(b, e.name)
+	}
+	if e.pid != 0 {
+		b = append(b, " pid="...)
+		b = strconv.AppendInt(b, int64(e.pid), 10)
+	}
+	if e.goid != 0 {
+		b = append(b, " goid="...)
+		b = strconv.AppendUint(b, e.goid, 10)
+	}
+	if e.caller != "" {
+		b = append(b, " caller="...)
+		b = appendValue(b, e.caller)
+	}
+	b = append(b, " msg="...)
+	b = appendValue(b, e.msg)
+	b = appendFields(b, e.fields, layout)
+	if e.stack != "" {
+		b = append(b, " stack="...)
+		b = appendValue(b, e.stack)
+	}
+
+	return b
+}
+
+// WithStacktrace adds stack trace of the logging goroutine to messages of minLevel and more severe levels,
+// e.g. WithStacktrace(ErrorLevel) for error and fatal messages, DisabledLevel stands for fatal only.
+// Stack is written as block of indented lines after the message in TextFormat
+// and as "stack" key in JSONFormat and LogfmtFormat. It is disabled by default.
+func WithStacktrace(minLevel Level) Option {
+	return func(l *logger) {
+		l.stackLevel = minLevel
+	}
+}
+
+// WithStacktraceTrim removes frames of this package from the top of stack traces (see WithStacktrace)
+// and frames of runtime (e.g. runtime.goexit) from the bottom, so traces start at the logging call.
+// Default is disabled, i.e. traces are complete as returned by runtime.Stack.
+func WithStacktraceTrim(enabled bool) Option {
+	return func(l *logger) {
+		l.stackTrim = enabled
+	}
+}
+
+// stack returns stack trace of the calling goroutine if messages of lv should contain it.
+func (l *logger) stack(lv Level) string {
+	if l.stackLevel == InvalidLevel || lv > l.stackLevel {
+		return ""
+	}
+
+	buf := make([]byte, 4096)
+	for {
+		n := runtime.Stack(buf, false)
+		if n < len(buf) {
+			if l.stackTrim {
+				return trimStack(string(buf[:n]))
+			}
+			return string(buf[:n])
+		}
+		buf = make([]byte, 2*len(buf))
+	}
+}
+
+// packageDir is the source directory of this package, frames of its non-test files are trimmed.
+var packageDir = func() string {
+	_, file, _, _ := runtime.Caller(0)
+	return filepath.Dir(file)
+}()
+
+// trimStack removes leading frames of this package and trailing runtime frames from stack
+// formatted by runtime.Stack: header line followed by pairs of function and "\tfile:line +0x.." lines.
+func trimStack(stack string) string {
+	lines := strings.Split(strings.TrimSuffix(stack, "\n"), "\n")
+	if len(lines) < 3 {
+		return stack
+	}
+	header, frames := lines[0], lines[1:]
+
+	for len(frames) >= 2 && internalFrame(frames[1]) {
+		frames = frames[2:]
+	}
+	for len(frames) >= 2 && strings.HasPrefix(frames[len(frames)-2], "runtime.") {
+		frames = frames[:len(frames)-2]
+	}
+
+	return header + "\n" + strings.Join(frames, "\n") + "\n"
+}
+
+// internalFrame reports whether file line of stack frame refers to non-test file of this package.
+func internalFrame(fileLine string) bool {
+	file := strings.TrimSpace(fileLine)
+	if i := strings.LastIndexByte(file, ':'); i > 0 {
+		file = file[:i]
+	}
+	return filepath.Dir(file) == packageDir && !strings.HasSuffix(file, "_test.go")
+}
+
+// jsonLevel returns value of "level" key, DisabledLevel stands for fatal messages.
+func jsonLevel(lv Level) string {
+	if lv == DisabledLevel {
+		return "fatal"
+	}
+	return lv.String()
+}
+
+// appendStamp appends current time formatted according to l.flags
+// the same way as log.Logger formats its header.
+func (l *logger) appendStamp(b []byte) []byte {
+	if l.flags&(log.Ldate|log.Ltime|log.Lmicroseconds) == 0 {
+		return b
+	}
+
+	b = l.time().AppendFormat(b, l.timeLayout())
+	return append(b, l.sep()...)
+}
+
+// timeLayout returns layout of timestamp given by WithTimeFormat or l.flags, e.g. "2006/01/02 15:04:05".
+// It is used for time fields too, RFC3339 is used if flags contain neither date nor time.
+func (l *logger) timeLayout() string {
+	if l.timeFormat != "" {
+		return l.timeFormat
+	}
+
+	switch l.flags & (log.Ldate | log.Ltime | log.Lmicroseconds) {
+	case 0:
+		return time.RFC3339
+	case log.Ldate:
+		return "2006/01/02"
+	case log.Ltime:
+		return "15:04:05"
+	case log.Ldate | log.Ltime:
+		return "2006/01/02 15:04:05"
+	}
+
+	// log.Lmicroseconds implies time
+	if l.flags&log.Ldate != 0 {
+		return "2006/01/02 15:04:05.000000"
+	}
+	return "15:04:05.000000"
+}
+
+// jsonLayout returns layout of "time" key and time fields in JSON entries (ts key in logfmt),
+// layout of WithTimeFormat is used in all formats.
+func (l *logger) jsonLayout() string {
+	if l.timeFormat != "" {
+		return l.timeFormat
+	}
+	if l.flags&log.Lmicroseconds != 0 {
+		return time.RFC3339Nano
+	}
+	return time.RFC3339
+}
+
+// jsonTime returns value of "time" key, empty if flags contain neither date nor time.
+func (l *logger) jsonTime() string {
+	if l.flags&(log.Ldate|log.Ltime|log.Lmicroseconds) == 0 {
+		return ""
+	}
+	return l.time().Format(l.jsonLayout())
+}
+
+// time returns current time in zone given by l.flags.
+func (l *logger) time() time.Time {
+	t := l.now()
+	if l.flags&log.LUTC != 0 {
+		t = t.UTC()
+	}
+	return t
+}
+
+// bufPool holds buffers used to encode entries.
+var bufPool = sync.Pool{
+	New: func() interface{} {
+		b := make([]byte, 0, 256)
+		return &b
+	},
+}
+
+func getBuffer() *[]byte {
+	b := bufPool.Get().(*[]byte)
+	*b = (*b)[:0]
+	return b
+}
+
+func putBuffer(b *[]byte) {
+	if cap(*b) > 64<<10 {
+		return // don't keep huge buffers
+	}
+	bufPool.Put(b)
+}
@@ -0,0 +1,303 @@
+package clog
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"regexp"
+	"runtime"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestJSONFormat(t *testing.T) {
+	buf := &bytes.Buffer{}
+	l, err := New(buf, "info", false, WithClock(testNow), WithFormat(JSONFormat))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	ts := testNow().Format(time.RFC3339)
+
+	l.Named("db").Infow("connected", Str("host", "local host"), Int("port", 5432))
+	l.Warn("line\nbreak")
+	l.ErrorErr(errors.New("foo"), "failed")
+	expected := []string{
+		`{"time":"` + ts + `","level":"info","name":"db","msg":"connected","host":"local host","port":5432}`,
+		`{"time":"` + ts + `","level":"warning","msg":"line\nbreak"}`,
+		`{"time":"` + ts + `","level":"error","msg":"failed","error":"foo"}`,
+	}
+	got := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if strings.Join(got, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("expected:\n%s\ngot:\n%s", strings.Join(expected, "\n"), buf.String())
+	}
+	for _, s := range got {
+		if !json.Valid([]byte(s)) {
+			t.Errorf("invalid JSON: %s", s)
+		}
+	}
+}
+
+func TestJSONFormatDebug(t *testing.T) {
+	buf := &bytes.Buffer{}
+	l, err := New(buf, "debug", false, WithClock(testNow), WithFormat(JSONFormat), WithCallerPath(ShortPath))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	l.Debug("details")
+	var e struct {
+		Level  string
+		Pid    int
+		Caller string
+		Msg    string
+	}
+	if err := json.Unmarshal(buf.Bytes(), &e); err != nil {
+		t.Fatalf("unexpected error: %v, got: %s", err, buf.String())
+	}
+	if e.Level != "debug" || e.Pid != os.Getpid() || !strings.HasPrefix(e.Caller, "entry_test.go:") || e.Msg != "details" {
+		t.Errorf("unexpected entry: %s", buf.String())
+	}
+}
+
+func TestTimeLayout(t *testing.T) {
+	tests := []struct {
+		flags    int
+		expected string
+	}{
+		{0, time.RFC3339},
+		{log.Ldate, "2006/01/02"},
+		{log.Ltime, "15:04:05"},
+		{log.Ldate | log.Ltime, "2006/01/02 15:04:05"},
+		{log.Ldate | log.Lmicroseconds, "2006/01/02 15:04:05.000000"},
+		{log.Ltime | log.Lmicroseconds | log.LUTC, "15:04:05.000000"},
+	}
+	for _, tt := range tests {
+		l := &logger{flags: tt.flags}
+		if got := l.timeLayout(); got != tt.expected {
+			t.Errorf("flags %d: expected: %q, got: %q", tt.flags, tt.expected, got)
+		}
+	}
+}
+
+func TestLogfmtFormat(t *testing.T) {
+	buf := &bytes.Buffer{}
+	l, err := New(buf, "info", false, WithClock(testNow), WithFormat(LogfmtFormat), WithConsole(false))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	ts := testNow().Format(time.RFC3339)
+
+	l.Named("db").Infow("connected to db", Str("dsn", "host=local port=5432"), Int("n", 2), Str("q", `say "hi"`))
+	l.Warn("plain")
+	l.Error("")
+	expected := []string{
+		`ts=` + ts + ` level=info name=db msg="connected to db" dsn="host=local port=5432" n=2 q="say \"hi\""`,
+		`ts=` + ts + ` level=warning msg=plain`,
+		`ts=` + ts + ` level=error msg=""`,
+	}
+	got := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if strings.Join(got, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("expected:\n%s\ngot:\n%s", strings.Join(expected, "\n"), buf.String())
+	}
+}
+
+func TestLogfmtFormatDebug(t *testing.T) {
+	buf := &bytes.Buffer{}
+	l, err := New(buf, "debug", false, WithFormat(LogfmtFormat), WithCallerPath(ShortPath))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	internal(l).flags = 0 // no ts
+
+	l.WithPrefix("app").Debug("x=1")
+	prefix := "level=debug prefix=app pid=" + strconv.Itoa(os.Getpid()) + " caller=entry_test.go:"
+	if got := buf.String(); !strings.HasPrefix(got, prefix) || !strings.HasSuffix(got, ` msg="x=1"`+"\n") {
+		t.Errorf("expected %q... msg=\"x=1\", got: %q", prefix, got)
+	}
+}
+
+func TestWithStacktrace(t *testing.T) {
+	buf := &bytes.Buffer{}
+	l, err := New(buf, "info", false, WithClock(testNow), WithConsole(false), WithStacktrace(ErrorLevel))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	l.Warn("no stack")
+	if strings.Contains(buf.String(), "\n\t") {
+		t.Errorf("warning: unexpected stack: %q", buf.String())
+	}
+
+	buf.Reset()
+	l.Error("with stack")
+	lines := strings.Split(buf.String(), "\n")
+	if lines[0] != "ERROR: 2017/03/09 14:05:07 with stack" {
+		t.Errorf("unexpected first line: %q", lines[0])
+	}
+	if !strings.Contains(buf.String(), "\tgithub.com/profioss/clog.TestWithStacktrace(") {
+		t.Errorf("expected stack with calling function, got:\n%s", buf.String())
+	}
+	for _, line := range lines[1 : len(lines)-1] {
+		if !strings.HasPrefix(line, "\t") {
+			t.Errorf("expected indented stack line, got: %q", line)
+		}
+	}
+}
+
+func TestWithStacktraceTrim(t *testing.T) {
+	buf := &bytes.Buffer{}
+	l, err := New(buf, "info", false, WithClock(testNow), WithConsole(false),
+		WithStacktrace(ErrorLevel), WithStacktraceTrim(true))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	l.Error("with stack")
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	if len(lines) < 4 || !strings.HasPrefix(lines[1], "\tgoroutine ") {
+		t.Fatalf("expected stack, got:\n%s", buf.String())
+	}
+	if !strings.HasPrefix(lines[2], "\tgithub.com/profioss/clog.TestWithStacktraceTrim(") ||
+		!strings.Contains(lines[3], "entry_test.go:") {
+		t.Errorf("expected first frame of test function, got:\n%s", buf.String())
+	}
+	if last := lines[len(lines)-2]; strings.HasPrefix(last, "\truntime.") {
+		t.Errorf("expected no trailing runtime frame, got:\n%s", buf.String())
+	}
+}
+
+func TestWithStacktraceJSON(t *testing.T) {
+	buf := &bytes.Buffer{}
+	l, err := New(buf, "info", false, WithConsole(false), WithFormat(JSONFormat), WithStacktrace(ErrorLevel))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	l.Error("with stack")
+	var e struct{ Stack string }
+	if err := json.Unmarshal(buf.Bytes(), &e); err != nil {
+		t.Fatalf("unexpected error: %v, got: %s", err, buf.String())
+	}
+	if !strings.Contains(e.Stack, "clog.TestWithStacktraceJSON(") {
+		t.Errorf("expected stack with calling function, got: %q", e.Stack)
+	}
+}
+
+func TestConsoleFormat(t *testing.T) {
+	buf := &bytes.Buffer{}
+	stdout, stderr := captureConsole(t, func() {
+		l, err := New(buf, "info", true, WithClock(testNow), WithFormat(JSONFormat), WithConsoleFormat(TextFormat))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		l.Infow("connected", Str("db", "main"))
+		l.Error("failed")
+		l.Named("sub").Clone().Warn("slow")
+	})
+
+	expected := `{"time":"2017-03-09T14:05:07Z","level":"info","msg":"connected","db":"main"}` + "\n" +
+		`{"time":"2017-03-09T14:05:07Z","level":"error","msg":"failed"}` + "\n" +
+		`{"time":"2017-03-09T14:05:07Z","level":"warning","name":"sub","msg":"slow"}` + "\n"
+	if buf.String() != expected {
+		t.Errorf("storage expected: %q, got: %q", expected, buf.String())
+	}
+	if expected := "INFO:  2017/03/09 14:05:07 connected db=main\n"; stdout != expected {
+		t.Errorf("stdout expected: %q, got: %q", expected, stdout)
+	}
+	if expected := "ERROR: 2017/03/09 14:05:07 failed\nWARN:  2017/03/09 14:05:07 sub: slow\n"; stderr != expected {
+		t.Errorf("stderr expected: %q, got: %q", expected, stderr)
+	}
+}
+
+func TestWithSeparator(t *testing.T) {
+	buf := &bytes.Buffer{}
+	l, err := New(buf, "debug", false, WithClock(testNow), WithConsole(false), WithSeparator(" | "))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	l.Named("db").Debug("connected")
+	re := regexp.MustCompile(fmt.Sprintf(`^DEBUG: 2017/03/09 14:05:07 \| \[%d\] \| \S*entry_test\.go:\d+ \| db: \| connected\n$`, os.Getpid()))
+	if !re.MatchString(buf.String()) {
+		t.Errorf("expected segments separated by \" | \", got: %q", buf.String())
+	}
+
+	// default is single space
+	buf.Reset()
+	l, err = New(buf, "info", false, WithClock(testNow), WithConsole(false), WithPID(true))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	l.Named("db").Info("connected")
+	if expected := fmt.Sprintf("INFO:  2017/03/09 14:05:07 [%d] db: connected\n", os.Getpid()); buf.String() != expected {
+		t.Errorf("expected: %q, got: %q", expected, buf.String())
+	}
+}
+
+func TestMessageColumn(t *testing.T) {
+	for _, short := range []bool{false, true} {
+		buf := &bytes.Buffer{}
+		opts := []Option{WithClock(testNow), WithConsole(false), WithMessageColumn(40)}
+		if short {
+			opts = append(opts, WithShortLevelPrefixes())
+		}
+		l, err := New(buf, "info", false, opts...)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		l.Info("info message")
+		l.Error("error message")
+		l.Named("database").Warn("warning message")
+		l.Named(strings.Repeat("x", 40)).Info("long name")
+
+		lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+		for i, msg := range []string{"info message", "error message", "warning message"} {
+			if len(lines[i]) < 40 || lines[i][40:] != msg || lines[i][39] != ' ' {
+				t.Errorf("short %v: expected %q at column 40, got: %q", short, msg, lines[i])
+			}
+		}
+		if !strings.HasSuffix(lines[3], strings.Repeat("x", 40)+": long name") {
+			t.Errorf("short %v: expected long decorations unpadded, got: %q", short, lines[3])
+		}
+	}
+}
+
+func TestComposePublic(t *testing.T) {
+	tests := []struct {
+		level    Level
+		pid      int
+		caller   string
+		msg      []interface{}
+		expected string
+	}{
+		{InfoLevel, 0, "", []interface{}{"connected"}, "INFO:  connected"},
+		{DebugLevel, 42, "main.go:10", []interface{}{"n=", 5}, "DEBUG: [42] main.go:10 n=5"},
+		{DisabledLevel, 0, "", []interface{}{"bye"}, "FATAL: bye"},
+		{WarnLevel, 7, "", []interface{}{1, 2}, "WARN:  [7] 1 2"},
+	}
+	for _, tt := range tests {
+		if got := Compose(tt.level, tt.pid, tt.caller, tt.msg...); got != tt.expected {
+			t.Errorf("expected: %q, got: %q", tt.expected, got)
+		}
+	}
+
+	// matches output of logger
+	buf := &bytes.Buffer{}
+	l, err := New(buf, "debug", false, WithConsole(false), WithCallerPath(ShortPath))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	internal(l).flags = 0
+	_, _, line, _ := runtime.Caller(0)
+	l.Debug("n=", 5)
+	expected := Compose(DebugLevel, os.Getpid(), "entry_test.go:"+strconv.Itoa(line+1), "n=", 5) + "\n"
+	if buf.String() != expected {
+		t.Errorf("expected: %q, got: %q", expected, buf.String())
+	}
+}
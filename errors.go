@@ -0,0 +1,104 @@
+//
+// Copyright 2017 Radovan Vrždiak. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package clog
+
+import (
+	"errors"
+	"fmt"
+)
+
+// FieldsError is error carrying structured context. If err logged by ErrorErr
+// or as value of field (e.g. Errorw("failed", clog.Err(err))) is FieldsError or wraps one (see errors.As),
+// its fields sorted by key are added to the entry after the error:
+//
+//	failed error="query failed" table=users attempt=3
+type FieldsError interface {
+	error
+	Fields() map[string]interface{}
+}
+
+// ErrorErr is for error messages describing err.
+// It appends err as "error" field and, if err wraps other errors,
+// the chain of wrapped errors (see errors.Unwrap) as "error_cause" field,
+// fields of FieldsError follow:
+//
+//	failed error="open: config: no such file" error_cause=["config: no such file","no such file"]
+//
+// If err is nil only the message is logged.
+func (l *logger) ErrorErr(err error, msg ...interface{}) {
+	if l == nil || !l.levelEnabled(ErrorLevel) || l.error == nil {
+		return // Don't log at lower levels.
+	}
+	e := l.compose(msg...)
+	e.fields = appendFieldList(e.fields, errorFields(err))
+	l.print(ErrorLevel, l.error, e)
+}
+
+// Err is for error messages which are returned as well:
+//
+//	return l.Err("open %s: %w", name, err)
+//
+// It returns error formatted by fmt.Errorf (so %w wraps err) and writes its text as error message.
+// The error is returned even if error messages are not written.
+func (l *logger) Err(format string, args ...interface{}) error {
+	err := fmt.Errorf(format, args...)
+	if l == nil || !l.levelEnabled(ErrorLevel) || l.error == nil {
+		return err // Don't log at lower levels.
+	}
+	l.print(ErrorLevel, l.error, l.composew(err.Error(), nil))
+	return err
+}
+
+// errorFields returns fields describing err and its chain of causes.
+func errorFields(err error) []Field {
+	if err == nil {
+		return nil
+	}
+
+	fields := []Field{{Key: "error", Value: err.Error()}}
+	var chain []string
+	for e := errors.Unwrap(err); e != nil; e = errors.Unwrap(e) {
+		chain = append(chain, e.Error())
+	}
+	if len(chain) > 0 {
+		fields = append(fields, Field{Key: "error_cause", Value: chain})
+	}
+
+	return append(fields, errorContext(err)...)
+}
+
+// errorContext returns fields of FieldsError in chain of err, nil if there is none.
+func errorContext(err error) []Field {
+	var fe FieldsError
+	if !errors.As(err, &fe) {
+		return nil
+	}
+	return sortedFields(fe.Fields())
+}
+
+// expandErrorFields returns fields with fields of FieldsError values added after them.
+// Fields are returned unchanged if there is no such value.
+func expandErrorFields(fields []Field) []Field {
+	var expanded []Field
+	for i, f := range fields {
+		var ctx []Field
+		if err, ok := f.Value.(error); ok {
+			ctx = errorContext(err)
+		}
+		if len(ctx) > 0 && expanded == nil {
+			expanded = append(make([]Field, 0, len(fields)+len(ctx)), fields[:i]...)
+		}
+		if expanded != nil {
+			expanded = append(expanded, f)
+			expanded = append(expanded, ctx...)
+		}
+	}
+
+	if expanded == nil {
+		return fields
+	}
+	return expanded
+}
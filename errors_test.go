@@ -0,0 +1,109 @@
+package clog
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestErrorErr(t *testing.T) {
+	root := errors.New("no such file")
+	wrapped := fmt.Errorf("open: %w", fmt.Errorf("config: %w", root))
+
+	tests := []struct {
+		err      error
+		msg      []interface{}
+		expected string
+	}{
+		{wrapped, []interface{}{"failed"},
+			`failed error="open: config: no such file" error_cause=["config: no such file","no such file"]`},
+		{root, []interface{}{"failed"}, `failed error="no such file"`},
+		{wrapped, nil, `error="open: config: no such file" error_cause=["config: no such file","no such file"]`},
+		{nil, []interface{}{"nothing", "wrong"}, `nothingwrong`},
+	}
+
+	for _, tt := range tests {
+		buf := &bytes.Buffer{}
+		l, err := NewMulti([]Sink{{Writer: buf, Level: ErrorLevel}}, WithClock(testNow))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		l.ErrorErr(tt.err, tt.msg...)
+		expected := "ERROR: 2017/03/09 14:05:07 " + tt.expected + "\n"
+		if buf.String() != expected {
+			t.Errorf("expected: %q, got: %q", expected, buf.String())
+		}
+	}
+
+	buf := &bytes.Buffer{}
+	l, _ := NewMulti([]Sink{{Writer: buf, Level: DisabledLevel}})
+	l.ErrorErr(wrapped, "disabled")
+	if buf.Len() != 0 {
+		t.Errorf("expected no output at disabled level, got: %q", buf.String())
+	}
+}
+
+func TestErr(t *testing.T) {
+	l, buf := NewTestLogger(InfoLevel)
+	internal(l).flags = 0
+
+	cause := errors.New("no such file")
+	err := l.Err("open %s: %w", "app.conf", cause)
+	if !errors.Is(err, cause) {
+		t.Errorf("expected error wrapping %v, got: %v", cause, err)
+	}
+	if expected := "ERROR: " + err.Error() + "\n"; buf.String() != expected {
+		t.Errorf("expected: %q, got: %q", expected, buf.String())
+	}
+
+	l, buf = NewTestLogger(DisabledLevel)
+	if err := l.Err("n=%d", 5); err == nil || err.Error() != "n=5" {
+		t.Errorf("expected error %q at disabled level, got: %v", "n=5", err)
+	}
+	if buf.Len() != 0 {
+		t.Errorf("expected nothing at disabled level, got: %q", buf.String())
+	}
+}
+
+// queryError carries structured context.
+type queryError struct {
+	table   string
+	attempt int
+}
+
+func (e queryError) Error() string { return "query failed" }
+
+func (e queryError) Fields() map[string]interface{} {
+	return map[string]interface{}{"table": e.table, "attempt": e.attempt}
+}
+
+func TestFieldsError(t *testing.T) {
+	qerr := queryError{table: "users", attempt: 3}
+	wrapped := fmt.Errorf("load: %w", qerr)
+
+	buf := &bytes.Buffer{}
+	l, err := New(buf, "info", false, WithClock(testNow), WithConsole(false))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	l.ErrorErr(qerr, "failed")
+	l.Errorw("failed", Err(wrapped), Int("n", 1))
+	expected := "ERROR: 2017/03/09 14:05:07 failed error=\"query failed\" attempt=3 table=users\n" +
+		"ERROR: 2017/03/09 14:05:07 failed error=\"load: query failed\" attempt=3 table=users n=1\n"
+	if buf.String() != expected {
+		t.Errorf("expected: %q, got: %q", expected, buf.String())
+	}
+
+	buf.Reset()
+	l, err = New(buf, "info", false, WithClock(testNow), WithConsole(false), WithFormat(JSONFormat))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	l.Errorw("failed", Err(qerr))
+	expected = `{"time":"2017-03-09T14:05:07Z","level":"error","msg":"failed","error":"query failed","attempt":3,"table":"users"}` + "\n"
+	if buf.String() != expected {
+		t.Errorf("expected: %q, got: %q", expected, buf.String())
+	}
+}
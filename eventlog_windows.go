@@ -0,0 +1,157 @@
+//
+// Copyright 2017 Radovan Vrždiak. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build windows
+// +build windows
+
+package clog
+
+import (
+	"strings"
+	"syscall"
+	"unsafe"
+)
+
+// NewEventLog creates new Logger like New writing to Windows Event Log as events of source.
+// Error and fatal messages are written as Error events, warnings as Warning events,
+// info and debug messages as Information events, all with event ID 1.
+// Event Log adds timestamp itself, so entries have neither timestamp nor level prefix
+// unless opts (e.g. WithFlags) say otherwise.
+//
+// The source should be registered (e.g. by installer using eventcreate or New-EventLog)
+// otherwise Event Viewer shows the message with a note that the event description is not found.
+// It returns nil Logger and error if level is not valid or the source can't be opened.
+func NewEventLog(source, level string, verbose bool, opts ...Option) (Logger, error) {
+	if _, err := LevelFromString(level); err != nil {
+		return nil, err
+	}
+
+	ev, err := openEventSource(source)
+	if err != nil {
+		return nil, err
+	}
+	return newEventLog(ev, level, verbose, opts)
+}
+
+// newEventLog creates Logger writing to ev.
+func newEventLog(ev eventWriter, level string, verbose bool, opts []Option) (Logger, error) {
+	opts = append([]Option{WithFlags(0), WithoutStorageLevelPrefix()}, opts...)
+	l, err := New(&eventLogWriter{ev: ev}, level, verbose, opts...)
+	if err != nil {
+		ev.Close()
+		return nil, err
+	}
+	return l, nil
+}
+
+// eventWriter writes events of one source, it has the method set of eventlog.Log of golang.org/x/sys.
+type eventWriter interface {
+	Error(eid uint32, msg string) error
+	Warning(eid uint32, msg string) error
+	Info(eid uint32, msg string) error
+	Close() error
+}
+
+// eventLogWriter writes every entry as one event of type given by level.
+type eventLogWriter struct {
+	ev eventWriter
+}
+
+// Write writes p as Information event.
+func (w *eventLogWriter) Write(p []byte) (int, error) {
+	return w.WriteLevel(InfoLevel, p)
+}
+
+// WriteLevel writes p as event of type given by lv, trailing newline is removed.
+func (w *eventLogWriter) WriteLevel(lv Level, p []byte) (int, error) {
+	const eid = 1
+	msg := strings.TrimSuffix(string(p), "\n")
+
+	var err error
+	switch lv {
+	case DisabledLevel, ErrorLevel:
+		err = w.ev.Error(eid, msg)
+	case WarnLevel:
+		err = w.ev.Warning(eid, msg)
+	default:
+		err = w.ev.Info(eid, msg)
+	}
+	if err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// Close deregisters event source.
+func (w *eventLogWriter) Close() error {
+	return w.ev.Close()
+}
+
+var (
+	advapi32                  = syscall.NewLazyDLL("advapi32.dll")
+	procRegisterEventSourceW  = advapi32.NewProc("RegisterEventSourceW")
+	procDeregisterEventSource = advapi32.NewProc("DeregisterEventSource")
+	procReportEventW          = advapi32.NewProc("ReportEventW")
+)
+
+// types of events, see ReportEventW
+const (
+	eventlogErrorType       = 0x0001
+	eventlogWarningType     = 0x0002
+	eventlogInformationType = 0x0004
+)
+
+// eventSource is eventWriter using Windows API directly, so there is no dependency on golang.org/x/sys.
+type eventSource struct {
+	handle syscall.Handle
+}
+
+func openEventSource(source string) (*eventSource, error) {
+	name, err := syscall.UTF16PtrFromString(source)
+	if err != nil {
+		return nil, err
+	}
+
+	h, _, err := procRegisterEventSourceW.Call(0, uintptr(unsafe.Pointer(name)))
+	if h == 0 {
+		return nil, err
+	}
+	return &eventSource{handle: syscall.Handle(h)}, nil
+}
+
+func (s *eventSource) report(etype uint16, eid uint32, msg string) error {
+	str, err := syscall.UTF16PtrFromString(msg)
+	if err != nil {
+		return err
+	}
+
+	strs := []*uint16{str}
+	r, _, err := procReportEventW.Call(uintptr(s.handle), uintptr(etype), 0, uintptr(eid), 0,
+		uintptr(len(strs)), 0, uintptr(unsafe.Pointer(&strs[0])), 0)
+	if r == 0 {
+		return err
+	}
+	return nil
+}
+
+func (s *eventSource) Error(eid uint32, msg string) error {
+	return s.report(eventlogErrorType, eid, msg)
+}
+
+func (s *eventSource) Warning(eid uint32, msg string) error {
+	return s.report(eventlogWarningType, eid, msg)
+}
+
+func (s *eventSource) Info(eid uint32, msg string) error {
+	return s.report(eventlogInformationType, eid, msg)
+}
+
+func (s *eventSource) Close() error {
+	r, _, err := procDeregisterEventSource.Call(uintptr(s.handle))
+	if r == 0 {
+		return err
+	}
+	return nil
+}
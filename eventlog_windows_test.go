@@ -0,0 +1,65 @@
+//go:build windows
+// +build windows
+
+package clog
+
+import (
+	"fmt"
+	"testing"
+)
+
+// fakeEvents records events as "type: message".
+type fakeEvents struct {
+	events []string
+	closed bool
+}
+
+func (f *fakeEvents) Error(eid uint32, msg string) error {
+	f.events = append(f.events, "error: "+msg)
+	return nil
+}
+
+func (f *fakeEvents) Warning(eid uint32, msg string) error {
+	f.events = append(f.events, "warning: "+msg)
+	return nil
+}
+
+func (f *fakeEvents) Info(eid uint32, msg string) error {
+	f.events = append(f.events, "info: "+msg)
+	return nil
+}
+
+func (f *fakeEvents) Close() error {
+	f.closed = true
+	return nil
+}
+
+func TestEventLog(t *testing.T) {
+	ev := &fakeEvents{}
+	l, err := newEventLog(ev, "info", false, []Option{WithConsole(false)})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	internal(l).exit = func(int) {}
+
+	l.Info("started")
+	l.Debug("hidden")
+	l.Warn("slow")
+	l.Error("failed")
+	l.Fatal("bye")
+	l.Close()
+
+	(&eventLogWriter{ev: ev}).WriteLevel(DebugLevel, []byte("debug\n"))
+
+	expected := []string{"info: started", "warning: slow", "error: failed", "error: bye", "info: debug"}
+	if fmt.Sprint(ev.events) != fmt.Sprint(expected) {
+		t.Errorf("expected: %q, got: %q", expected, ev.events)
+	}
+	if !ev.closed {
+		t.Error("expected event source closed by Close")
+	}
+
+	if _, err := newEventLog(&fakeEvents{}, "invalid", false, nil); err == nil {
+		t.Error("expected error of invalid level")
+	}
+}
@@ -0,0 +1,147 @@
+//
+// Copyright 2017 Radovan Vrždiak. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package clog
+
+import (
+	"io"
+	"os"
+	"sync/atomic"
+)
+
+// WithExitCode sets exit code used by Fatal methods. Default is 1.
+func WithExitCode(code int) Option {
+	return func(l *logger) {
+		l.exitCode = code
+	}
+}
+
+// WithExitFunc replaces os.Exit called by Fatal methods after the message is written and flushed,
+// e.g. by function recording exit code in tests. Fatal methods return if fn returns,
+// fn may panic to stop the caller. Nil fn stands for os.Exit.
+func WithExitFunc(fn func(code int)) Option {
+	return func(l *logger) {
+		if fn == nil {
+			fn = os.Exit
+		}
+		l.exit = fn
+	}
+}
+
+// FatalPolicy decides what Fatal methods do after the message is written and flushed.
+type FatalPolicy int
+
+// Fatal policies.
+const (
+	FatalExit     FatalPolicy = iota // exit with exit code (see WithExitCode and WithExitFunc)
+	FatalPanic                       // panic with the message so embedder can recover
+	FatalCallback                    // call function of WithFatalCallback and return
+)
+
+// WithFatalPolicy sets what Fatal methods do after writing the message. Default is FatalExit.
+// FatalCallback without callback (see WithFatalCallback) behaves like FatalExit.
+func WithFatalPolicy(p FatalPolicy) Option {
+	return func(l *logger) {
+		l.fatalPolicy = p
+	}
+}
+
+// WithFatalCallback sets FatalCallback policy calling fn with fatal message and exit code
+// after the message is written, e.g. to stop supervised goroutine instead of the process.
+// Fatal methods return when fn returns.
+func WithFatalCallback(fn func(msg string, code int)) Option {
+	return func(l *logger) {
+		l.fatalPolicy = FatalCallback
+		l.fatalFn = fn
+	}
+}
+
+// exitFatal writes fatal message e, flushes all writers and exits the process (see FatalPolicy).
+func (l *logger) exitFatal(e entry) {
+	l.writeFatal(e)
+	l.afterFatal(e.msg)
+}
+
+// afterFatal applies fatal policy to written fatal message msg.
+func (l *logger) afterFatal(msg string) {
+	switch {
+	case l.fatalPolicy == FatalPanic:
+		panic(msg)
+	case l.fatalPolicy == FatalCallback && l.fatalFn != nil:
+		l.fatalFn(msg, l.exitCode)
+	default:
+		l.exit(l.exitCode)
+	}
+}
+
+// writeFatal writes fatal message e and flushes all writers.
+// The message is written before anything else so it is not lost if flushing blocks or fails.
+// After Close the message is written to stderr only so the process does not exit without a word.
+func (l *logger) writeFatal(e entry) {
+	e.stack = l.stack(DisabledLevel)
+	if l.isClosed() {
+		out := l.fatal.mirror
+		if out == nil {
+			out = l.newOutput(os.Stderr, DisabledLevel)
+		}
+		l.emit(out, e)
+		return
+	}
+	l.emit(l.fatal, e)
+	l.Sync()
+}
+
+// Sync writes summaries of messages suppressed by WithDedup
+// and flushes storage writers implementing Flush() error (e.g. *bufio.Writer)
+// and Sync() error (e.g. *os.File). Console (stdout, stderr) is not buffered and it is not synced.
+// It returns the first error encountered, ErrClosed after Close.
+func (l *logger) Sync() error {
+	if l == nil {
+		return nil
+	}
+	if l.closed != nil && atomic.LoadInt32(l.closed) != 0 {
+		return ErrClosed
+	}
+	return l.sync()
+}
+
+func (l *logger) sync() error {
+	if l.dedup != nil {
+		for lv, s := range l.dedup.flush() {
+			if out := l.outputFor(lv); out != nil {
+				l.emit(out, entry{msg: s, summary: true}) // not checked, Close syncs after l is marked closed
+			}
+		}
+	}
+
+	var first error
+	for _, w := range l.writers {
+		if err := syncWriter(w); err != nil && first == nil {
+			first = err
+		}
+	}
+
+	return first
+}
+
+func syncWriter(w io.Writer) error {
+	if s, ok := w.(*switchWriter); ok {
+		w = s.get()
+	}
+	if w == os.Stdout || w == os.Stderr {
+		return nil
+	}
+
+	if f, ok := w.(interface{ Flush() error }); ok {
+		if err := f.Flush(); err != nil {
+			return err
+		}
+	}
+	if s, ok := w.(interface{ Sync() error }); ok {
+		return s.Sync()
+	}
+
+	return nil
+}
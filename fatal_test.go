@@ -0,0 +1,161 @@
+package clog
+
+import (
+	"bufio"
+	"bytes"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFatalFlushesBeforeExit(t *testing.T) {
+	storage := &bytes.Buffer{}
+	buffered := bufio.NewWriterSize(storage, 4096)
+
+	lx, err := NewMulti([]Sink{{Writer: buffered, Level: InfoLevel}},
+		WithDedup(time.Hour), WithExitCode(3))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	l := lx.(*logger)
+
+	exitCode := -1
+	l.exit = func(code int) {
+		// everything must be already written when exiting
+		if !strings.Contains(storage.String(), "FATAL: ") {
+			t.Errorf("fatal message not flushed before exit: %q", storage.String())
+		}
+		exitCode = code
+	}
+
+	for i := 0; i < 5; i++ {
+		l.Info("repeated")
+	}
+	l.Fatal("giving up")
+
+	if exitCode != 3 {
+		t.Errorf("expected exit code 3, got %d", exitCode)
+	}
+
+	out := storage.String()
+	for _, s := range []string{"INFO:  ", "repeated", "last message repeated 4 times", "FATAL: ", "giving up"} {
+		if !strings.Contains(out, s) {
+			t.Errorf("expected %q in output, got: %q", s, out)
+		}
+	}
+	if strings.Index(out, "giving up") > strings.Index(out, "last message repeated") {
+		t.Errorf("fatal message should be written first: %q", out)
+	}
+}
+
+func TestSyncWriters(t *testing.T) {
+	storage := &bytes.Buffer{}
+	buffered := bufio.NewWriter(storage)
+	l, err := New(buffered, "info", false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	l.Info("buffered")
+	if storage.Len() != 0 {
+		t.Fatalf("expected buffered output, got: %q", storage.String())
+	}
+	if err := l.Sync(); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if !strings.Contains(storage.String(), "buffered") {
+		t.Errorf("expected flushed message, got: %q", storage.String())
+	}
+}
+
+func TestWithExitFunc(t *testing.T) {
+	buf := &bytes.Buffer{}
+	var codes []int
+	var written []string
+	l, err := New(buf, "info", false, WithClock(testNow), WithConsole(false), WithExitCode(2),
+		WithExitFunc(func(code int) {
+			codes = append(codes, code)
+			written = append(written, buf.String())
+		}))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	l.Fatalf("config %s not found", "app.conf")
+	l.Fatalw("bye", Int("n", 1))
+
+	if len(codes) != 2 || codes[0] != 2 || codes[1] != 2 {
+		t.Errorf("expected exit codes [2 2], got %v", codes)
+	}
+	expected := "FATAL: 2017/03/09 14:05:07 config app.conf not found\n"
+	if len(written) != 2 || written[0] != expected {
+		t.Errorf("expected %q written before exit, got: %q", expected, written)
+	}
+	if len(written) == 2 && !strings.HasSuffix(written[1], "FATAL: 2017/03/09 14:05:07 bye n=1\n") {
+		t.Errorf("expected fatal message with fields before exit, got: %q", written[1])
+	}
+}
+
+func TestFatalPolicy(t *testing.T) {
+	expected := "FATAL: 2017/03/09 14:05:07 disk full\n"
+
+	t.Run("exit", func(t *testing.T) {
+		buf := &bytes.Buffer{}
+		code := -1
+		l, err := New(buf, "info", false, WithClock(testNow), WithConsole(false), WithExitCode(4),
+			WithFatalPolicy(FatalExit), WithExitFunc(func(c int) {
+				if buf.String() != expected {
+					t.Errorf("expected: %q written before exit, got: %q", expected, buf.String())
+				}
+				code = c
+			}))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		l.Fatal("disk full")
+		if code != 4 {
+			t.Errorf("expected exit code 4, got %d", code)
+		}
+	})
+
+	t.Run("panic", func(t *testing.T) {
+		buf := &bytes.Buffer{}
+		l, err := New(buf, "info", false, WithClock(testNow), WithConsole(false),
+			WithFatalPolicy(FatalPanic), WithExitFunc(func(int) { t.Error("unexpected exit") }))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		defer func() {
+			r := recover()
+			if r != "disk full" {
+				t.Errorf("expected: %q, got: %v", "disk full", r)
+			}
+			if buf.String() != expected {
+				t.Errorf("expected: %q written before panic, got: %q", expected, buf.String())
+			}
+		}()
+		l.Fatalf("disk %s", "full")
+		t.Error("expected panic")
+	})
+
+	t.Run("callback", func(t *testing.T) {
+		buf := &bytes.Buffer{}
+		var got []string
+		l, err := New(buf, "info", false, WithClock(testNow), WithConsole(false), WithExitCode(5),
+			WithExitFunc(func(int) { t.Error("unexpected exit") }),
+			WithFatalCallback(func(msg string, code int) {
+				if buf.String() != expected {
+					t.Errorf("expected: %q written before callback, got: %q", expected, buf.String())
+				}
+				got = append(got, msg, strconv.Itoa(code))
+			}))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		l.Fatal("disk full")
+		if strings.Join(got, " ") != "disk full 5" {
+			t.Errorf("expected: %q, got: %q", "disk full 5", got)
+		}
+	})
+}
@@ -0,0 +1,345 @@
+//
+// Copyright 2017 Radovan Vrždiak. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package clog
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// Field is a key-value pair attached to a log message.
+// In text output fields are appended to the message as key=value,
+// in JSON output they are keys of the entry object.
+type Field struct {
+	Key   string
+	Value interface{}
+}
+
+// Str returns string field.
+func Str(key, val string) Field {
+	return Field{Key: key, Value: val}
+}
+
+// Int returns integer field.
+func Int(key string, val int) Field {
+	return Field{Key: key, Value: val}
+}
+
+// Duration returns duration field written like time.Duration.String, e.g. "14ms".
+func Duration(key string, d time.Duration) Field {
+	return Field{Key: key, Value: d}
+}
+
+// Time returns time field written in layout of the timestamp (RFC3339 in JSON).
+func Time(key string, t time.Time) Field {
+	return Field{Key: key, Value: t}
+}
+
+// Err returns field with key "error" and message of err as its value.
+func Err(err error) Field {
+	return Field{Key: "error", Value: err}
+}
+
+// WithFields returns child logger adding fields to every message after its own fields.
+// Fields are sorted by key so the output is stable, fields of nested children follow fields of parent.
+// Children share writers, level and options with l (see Named).
+func (l *logger) WithFields(fields map[string]interface{}) Logger {
+	if l == nil || len(fields) == 0 {
+		return l
+	}
+
+	child := l.child()
+	child.fields = appendFieldList(child.fields, sortedFields(fields))
+
+	return &child
+}
+
+// sortedFields returns fields of m sorted by key.
+func sortedFields(m map[string]interface{}) []Field {
+	sorted := make([]Field, 0, len(m))
+	for k, v := range m {
+		sorted = append(sorted, Field{Key: k, Value: v})
+	}
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Key < sorted[j].Key })
+
+	return sorted
+}
+
+// dynFields holds fields of SetFields, every logger has its own set.
+type dynFields struct {
+	mu     sync.RWMutex
+	fields []Field
+}
+
+func (d *dynFields) get() []Field {
+	if d == nil {
+		return nil
+	}
+
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	return d.fields
+}
+
+func (d *dynFields) set(fields []Field) {
+	d.mu.Lock()
+	d.fields = fields
+	d.mu.Unlock()
+}
+
+// SetFields replaces fields added to every message after fields of WithFields,
+// e.g. connection id of long-lived connection logger, without creating child loggers.
+// Fields are guarded by lock so SetFields is safe to call concurrently with logging.
+// Children created by Named, WithPrefix or WithFields get a copy of current fields and their own set.
+func (l *logger) SetFields(fields ...Field) {
+	if l == nil || l.dyn == nil {
+		return
+	}
+	l.dyn.set(append([]Field(nil), fields...))
+}
+
+// ClearFields removes fields of SetFields.
+func (l *logger) ClearFields() {
+	if l == nil || l.dyn == nil {
+		return
+	}
+	l.dyn.set(nil)
+}
+
+// allFields returns fields of WithFields followed by fields of SetFields.
+func (l *logger) allFields() []Field {
+	return appendFieldList(l.fields, l.dyn.get())
+}
+
+// child returns copy of l for child logger with own set of SetFields.
+func (l *logger) child() logger {
+	c := *l
+	c.fields = l.allFields()
+	c.dyn = &dynFields{}
+	return c
+}
+
+// appendFieldList returns fields followed by more. It never modifies backing array of fields
+// so fields shared by child loggers are safe.
+func appendFieldList(fields, more []Field) []Field {
+	if len(more) == 0 {
+		return fields
+	}
+	if len(fields) == 0 {
+		return more
+	}
+	return append(fields[:len(fields):len(fields)], more...)
+}
+
+// Fatalw is for fatal error messages with fields.
+func (l *logger) Fatalw(msg string, fields ...Field) {
+	if l == nil || l.fatal == nil {
+		return // Don't log at disabled level.
+	}
+	l.exitFatal(l.composew(msg, fields))
+}
+
+// Errorw is for error messages with fields.
+func (l *logger) Errorw(msg string, fields ...Field) {
+	if l == nil || !l.levelEnabled(ErrorLevel) || l.error == nil {
+		return // Don't log at lower levels.
+	}
+	l.print(ErrorLevel, l.error, l.composew(msg, fields))
+}
+
+// Warnw is for warning messages with fields.
+func (l *logger) Warnw(msg string, fields ...Field) {
+	if l == nil || !l.levelEnabled(WarnLevel) || l.warn == nil {
+		return // Don't log at lower levels.
+	}
+	l.print(WarnLevel, l.warn, l.composew(msg, fields))
+}
+
+// Infow is for info messages with fields.
+func (l *logger) Infow(msg string, fields ...Field) {
+	if l == nil || !l.levelEnabled(InfoLevel) || l.info == nil {
+		return // Don't log at lower levels.
+	}
+	l.print(InfoLevel, l.info, l.composew(msg, fields))
+}
+
+// Debugw is for debug messages with fields.
+func (l *logger) Debugw(msg string, fields ...Field) {
+	if l == nil || !l.levelEnabled(DebugLevel) || l.debug == nil {
+		return // Don't log at lower levels.
+	}
+	l.print(DebugLevel, l.debug, l.composew(msg, fields))
+}
+
+// emptyKey is written instead of empty field key.
+const emptyKey = "_"
+
+// appendFields appends fields to b as space separated key=value pairs.
+// Keys and values containing spaces, quotes, '=' or control characters are quoted,
+// empty key is written as emptyKey, time values are formatted by layout.
+func appendFields(b []byte, fields []Field, layout string) []byte {
+	for _, f := range fields {
+		if len(b) > 0 && b[len(b)-1] != ' ' {
+			b = append(b, ' ')
+		}
+		b = appendKey(b, f.Key)
+		b = append(b, '=')
+		b = appendFieldValue(b, f.Value, layout)
+	}
+	return b
+}
+
+func appendFieldValue(b []byte, v interface{}, layout string) []byte {
+	switch v := v.(type) {
+	case string:
+		return appendValue(b, v)
+	case int:
+		return strconv.AppendInt(b, int64(v), 10)
+	case time.Duration:
+		return append(b, v.String()...)
+	case time.Time:
+		return appendValue(b, v.Format(layout))
+	case error:
+		return appendValue(b, v.Error())
+	case []string:
+		b = append(b, '[')
+		for i, s := range v {
+			if i > 0 {
+				b = append(b, ',')
+			}
+			b = strconv.AppendQuote(b, s)
+		}
+		return append(b, ']')
+	}
+	return appendValue(b, fmt.Sprint(v))
+}
+
+func appendKey(b []byte, key string) []byte {
+	switch {
+	case key == "":
+		return append(b, emptyKey...)
+	case needsQuote(key):
+		return strconv.AppendQuote(b, key)
+	}
+	return append(b, key...)
+}
+
+// needsQuote reports whether s contains space, quote, '=' or control character.
+func needsQuote(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if c := s[i]; c <= ' ' || c == '=' || c == '"' || c == 0x7f {
+			return true
+		}
+	}
+	return false
+}
+
+func appendValue(b []byte, s string) []byte {
+	if s == "" || strings.ContainsAny(s, " =\"\t\r\n") {
+		return strconv.AppendQuote(b, s)
+	}
+	return append(b, s...)
+}
+
+// appendJSONValue appends v to b as JSON value, time values are formatted by layout.
+// Common types are encoded directly, others by json.Marshal
+// or as string given by fmt.Sprint if v can't be marshaled.
+func appendJSONValue(b []byte, v interface{}, layout string) []byte {
+	switch v := v.(type) {
+	case nil:
+		return append(b, "null"...)
+	case string:
+		return appendJSONString(b, v)
+	case bool:
+		return strconv.AppendBool(b, v)
+	case int:
+		return strconv.AppendInt(b, int64(v), 10)
+	case int64:
+		return strconv.AppendInt(b, v, 10)
+	case uint64:
+		return strconv.AppendUint(b, v, 10)
+	case float64:
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			return appendJSONString(b, strconv.FormatFloat(v, 'g', -1, 64))
+		}
+		return strconv.AppendFloat(b, v, 'g', -1, 64)
+	case time.Duration:
+		return appendJSONString(b, v.String())
+	case time.Time:
+		return appendJSONString(b, v.Format(layout))
+	case error:
+		return appendJSONString(b, v.Error())
+	case []string:
+		b = append(b, '[')
+		for i, s := range v {
+			if i > 0 {
+				b = append(b, ',')
+			}
+			b = appendJSONString(b, s)
+		}
+		return append(b, ']')
+	}
+
+	if p, err := json.Marshal(v); err == nil {
+		return append(b, p...)
+	}
+	return appendJSONString(b, fmt.Sprint(v))
+}
+
+// appendJSONString appends s to b as quoted JSON string.
+// Invalid UTF-8 is replaced by U+FFFD like encoding/json does.
+func appendJSONString(b []byte, s string) []byte {
+	const hex = "0123456789abcdef"
+
+	b = append(b, '"')
+	start := 0
+	for i := 0; i < len(s); {
+		c := s[i]
+		if c >= utf8.RuneSelf {
+			r, size := utf8.DecodeRuneInString(s[i:])
+			if r == utf8.RuneError && size == 1 {
+				b = append(b, s[start:i]...)
+				b = append(b, "\ufffd"...)
+				i += size
+				start = i
+				continue
+			}
+			i += size
+			continue
+		}
+		if c >= 0x20 && c != '"' && c != '\\' {
+			i++
+			continue
+		}
+
+		b = append(b, s[start:i]...)
+		switch c {
+		case '"', '\\':
+			b = append(b, '\\', c)
+		case '\n':
+			b = append(b, '\\', 'n')
+		case '\r':
+			b = append(b, '\\', 'r')
+		case '\t':
+			b = append(b, '\\', 't')
+		default:
+			b = append(b, '\\', 'u', '0', '0', hex[c>>4], hex[c&0xf])
+		}
+		i++
+		start = i
+	}
+	b = append(b, s[start:]...)
+
+	return append(b, '"')
+}
@@ -0,0 +1,212 @@
+package clog
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAppendFields(t *testing.T) {
+	fields := []Field{
+		{Key: "a", Value: 1},
+		{Key: "b", Value: "x y"},
+		{Key: "c", Value: "k=v"},
+		{Key: "d", Value: ""},
+	}
+	expected := `msg a=1 b="x y" c="k=v" d=""`
+	if got := string(appendFields([]byte("msg"), fields, "")); got != expected {
+		t.Errorf("expected: %q, got: %q", expected, got)
+	}
+}
+
+func TestAppendFieldsKeys(t *testing.T) {
+	fields := []Field{
+		{Key: "a b", Value: "c"},
+		{Key: "", Value: "v"},
+		{Key: "k=v", Value: 1},
+		{Key: `q"`, Value: 2},
+		{Key: "nl\n", Value: 3},
+		{Key: "ok_key.1", Value: 4},
+	}
+	expected := `msg "a b"=c _=v "k=v"=1 "q\""=2 "nl\n"=3 ok_key.1=4`
+	if got := string(appendFields([]byte("msg"), fields, "")); got != expected {
+		t.Errorf("expected: %q, got: %q", expected, got)
+	}
+
+	l, buf := NewTestLogger(InfoLevel, WithFlags(0), WithFormat(LogfmtFormat))
+	l.Infow("m", Int("", 1))
+	if !strings.HasSuffix(buf.String(), ` _=1`+"\n") {
+		t.Errorf("expected placeholder of empty key in logfmt, got: %q", buf.String())
+	}
+}
+
+func TestAppendFieldsSeparator(t *testing.T) {
+	fields := []Field{{Key: "a", Value: 1}, {Key: "b", Value: []string{"x y", "z"}}}
+	for _, prefix := range []string{"", "[123] "} {
+		expected := prefix + `a=1 b=["x y","z"]`
+		if got := string(appendFields([]byte(prefix), fields, "")); got != expected {
+			t.Errorf("expected: %q, got: %q", expected, got)
+		}
+	}
+}
+
+func TestFieldHelpers(t *testing.T) {
+	at := time.Date(2017, 3, 9, 14, 5, 7, 0, time.UTC)
+	tests := []struct {
+		field Field
+		text  string
+		json  string
+	}{
+		{Str("user", "john doe"), `user="john doe"`, `"user":"john doe"`},
+		{Str("empty", ""), `empty=""`, `"empty":""`},
+		{Int("n", -42), `n=-42`, `"n":-42`},
+		{Duration("dur", 14*time.Millisecond), `dur=14ms`, `"dur":"14ms"`},
+		{Duration("dur", 90*time.Second), `dur=1m30s`, `"dur":"1m30s"`},
+		{Time("at", at), `at="2017/03/09 14:05:07"`, `"at":"2017-03-09T14:05:07Z"`},
+		{Err(errors.New("open: \"x\"")), `error="open: \"x\""`, `"error":"open: \"x\""`},
+		{Err(nil), `error=<nil>`, `"error":null`},
+	}
+
+	for _, tt := range tests {
+		if got := string(appendFields(nil, []Field{tt.field}, "2006/01/02 15:04:05")); got != tt.text {
+			t.Errorf("text: expected: %s, got: %s", tt.text, got)
+		}
+		e := entry{fields: []Field{tt.field}}
+		expected := `{"level":"info","msg":"",` + tt.json + `}`
+		got := e.appendJSON(nil, InfoLevel, "", time.RFC3339)
+		if string(got) != expected {
+			t.Errorf("json: expected: %s, got: %s", expected, got)
+		}
+		if !json.Valid(got) {
+			t.Errorf("json: invalid %s", got)
+		}
+	}
+}
+
+func TestAppendJSONString(t *testing.T) {
+	for _, s := range []string{"plain", `q"b\`, "nl\nt\tcr\r", "\x00\x1f", "žluťoučký", "bad\xffutf"} {
+		expected, _ := json.Marshal(s)
+		if got := appendJSONString(nil, s); string(got) != string(expected) {
+			t.Errorf("%q: expected: %s, got: %s", s, expected, got)
+		}
+	}
+}
+
+func TestAppendJSONValue(t *testing.T) {
+	tests := []struct {
+		v        interface{}
+		expected string
+	}{
+		{nil, `null`},
+		{true, `true`},
+		{int64(-1), `-1`},
+		{uint64(1), `1`},
+		{1.5, `1.5`},
+		{math.NaN(), `"NaN"`},
+		{[]string{"a", "b"}, `["a","b"]`},
+		{map[string]int{"a": 1}, `{"a":1}`},
+	}
+	for _, tt := range tests {
+		if got := appendJSONValue(nil, tt.v, ""); string(got) != tt.expected {
+			t.Errorf("%v: expected: %s, got: %s", tt.v, tt.expected, got)
+		}
+	}
+
+	// values json can't marshal are written as strings
+	if got := appendJSONValue(nil, make(chan int), ""); !json.Valid(got) || got[0] != '"' {
+		t.Errorf("chan: expected string, got: %s", got)
+	}
+}
+
+func TestW(t *testing.T) {
+	buf := &bytes.Buffer{}
+	l, err := New(buf, "debug", false, WithClock(testNow), WithCallerPath(ShortPath))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	l = l.Named("db")
+
+	l.Infow("query", Str("table", "users"), Duration("dur", 14*time.Millisecond))
+	l.Errorw("failed", Err(errors.New("timeout")))
+	expected := []string{
+		`INFO:  2017/03/09 14:05:07 [%d] field_test.go:%d db: query table=users dur=14ms`,
+		`ERROR: 2017/03/09 14:05:07 [%d] field_test.go:%d db: failed error=timeout`,
+	}
+	got := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(got) != len(expected) {
+		t.Fatalf("expected %d lines, got:\n%s", len(expected), buf.String())
+	}
+	for i := range expected {
+		var pid, line int
+		if _, err := fmt.Sscanf(got[i], expected[i], &pid, &line); err != nil || pid != os.Getpid() {
+			t.Errorf("expected: %q, got: %q (%v)", expected[i], got[i], err)
+		}
+	}
+}
+
+func TestFieldOrder(t *testing.T) {
+	buf := &bytes.Buffer{}
+	l, err := New(buf, "info", false, WithClock(testNow))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	fields := map[string]interface{}{"zone": "eu", "app": "api", "host": "h1", "id": 7, "b": true}
+	child := l.WithFields(fields).WithFields(map[string]interface{}{"a": 1})
+
+	var first string
+	for i := 0; i < 50; i++ {
+		buf.Reset()
+		child.Infow("req", Int("z", 1), Str("m", "GET"), Int("a", 2))
+		if i == 0 {
+			first = buf.String()
+			continue
+		}
+		if buf.String() != first {
+			t.Fatalf("unstable output:\n%s\n%s", first, buf.String())
+		}
+	}
+
+	expected := "INFO:  2017/03/09 14:05:07 req app=api b=true host=h1 id=7 zone=eu a=1 z=1 m=GET a=2\n"
+	if first != expected {
+		t.Errorf("expected: %q, got: %q", expected, first)
+	}
+
+	buf.Reset()
+	child.StdLogger(InfoLevel).Print("std")
+	l.Info("parent")
+	expected = "INFO:  2017/03/09 14:05:07 std app=api b=true host=h1 id=7 zone=eu a=1\n" +
+		"INFO:  2017/03/09 14:05:07 parent\n"
+	if buf.String() != expected {
+		t.Errorf("expected: %q, got: %q", expected, buf.String())
+	}
+}
+
+func TestSetFields(t *testing.T) {
+	l, buf := NewTestLogger(InfoLevel)
+	internal(l).flags = 0
+	f := l.WithFields(map[string]interface{}{"app": "api"})
+
+	f.SetFields(Str("conn", "c1"), Str("peer", "10.0.0.1"))
+	f.Info("open")
+	f.Infow("read", Int("n", 5))
+	child := f.Named("tls")
+	f.ClearFields()
+	f.Info("closed")
+	child.Info("child")
+	l.Info("root")
+
+	expected := "INFO:  open app=api conn=c1 peer=10.0.0.1\n" +
+		"INFO:  read app=api conn=c1 peer=10.0.0.1 n=5\n" +
+		"INFO:  closed app=api\n" +
+		"INFO:  tls: child app=api conn=c1 peer=10.0.0.1\n" +
+		"INFO:  root\n"
+	if buf.String() != expected {
+		t.Errorf("expected: %q, got: %q", expected, buf.String())
+	}
+}
@@ -0,0 +1,146 @@
+//
+// Copyright 2017 Radovan Vrždiak. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package clog
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// OpenFile helper function opens log file with options suitable for logging i.e. O_APPEND, etc.
+func OpenFile(fname string) (fd *os.File, err error) {
+	return OpenFileWithOptions(fname, 0644, os.ModePerm, false)
+}
+
+// OpenFileWithOptions opens log file in append mode like OpenFile
+// with given permissions of the file and of created parent directories.
+// Both modes are subject to umask and must contain only permission bits,
+// file must be writable and directory accessible by the owner.
+//
+// If sync is true, file is opened with O_SYNC so every log entry reaches the disk
+// before the logging call returns. It makes logs durable in case of crash
+// (e.g. audit logs), but it makes every write considerably slower.
+func OpenFileWithOptions(fname string, fileMode, dirMode os.FileMode, sync bool) (fd *os.File, err error) {
+	if dirMode&^os.ModePerm != 0 || dirMode&0700 != 0700 {
+		return nil, fmt.Errorf("invalid log directory mode %v: permission bits with owner rwx expected", dirMode)
+	}
+	if err := checkLogFile(fname, fileMode); err != nil {
+		return nil, err
+	}
+
+	if dir := filepath.Dir(fname); dir != "." && dir != "" { // bare file name - nothing to create
+		err = os.MkdirAll(dir, dirMode)
+		if err != nil {
+			return
+		}
+	}
+
+	return openLogFile(fname, fileMode, sync)
+}
+
+// OpenFileNoMkdir opens log file in append mode like OpenFile but it does not create parent directories,
+// e.g. when directories are managed by deployment with restricted permissions.
+// It returns error if the directory does not exist.
+func OpenFileNoMkdir(fname string, fileMode os.FileMode) (*os.File, error) {
+	if err := checkLogFile(fname, fileMode); err != nil {
+		return nil, err
+	}
+
+	dir := filepath.Dir(fname)
+	if fi, err := os.Stat(dir); err != nil {
+		return nil, fmt.Errorf("log directory %q: %v", dir, err)
+	} else if !fi.IsDir() {
+		return nil, fmt.Errorf("log directory %q is not a directory", dir)
+	}
+
+	return openLogFile(fname, fileMode, false)
+}
+
+// NewWithFallback creates new Logger like New writing to log file fname (see OpenFile).
+// If the file can't be opened, it returns Logger writing all messages to stderr only
+// and logs a warning with the reason (if WarnLevel is enabled), invalid level falls back to InfoLevel the same way.
+// It never fails, e.g. for command line tools which prefer logging to stderr over crash.
+func NewWithFallback(fname, level string, verbose bool, opts ...Option) Logger {
+	lv, lvErr := LevelFromString(level)
+	if lvErr == nil {
+		lvErr = lv.Validate()
+	}
+	if lvErr != nil {
+		lv = InfoLevel
+	}
+
+	fd, err := OpenFile(fname)
+	if err == nil {
+		l, _ := NewWithLevel(fd, lv, verbose, opts...)
+		if lvErr != nil {
+			l.Warnf("%v, using %s level", lvErr, lv)
+		}
+		return l
+	}
+
+	l, _ := NewWithLevel(os.Stderr, lv, false, append(opts[:len(opts):len(opts)], WithConsole(false))...)
+	if lvErr != nil {
+		l.Warnf("%v, using %s level", lvErr, lv)
+	}
+	l.Warnf("log file %q can't be opened, logging to stderr: %v", fname, err)
+	return l
+}
+
+// checkLogFile returns error if fname or fileMode are not valid for log file.
+func checkLogFile(fname string, fileMode os.FileMode) error {
+	if fileMode&^os.ModePerm != 0 || fileMode&0200 == 0 {
+		return fmt.Errorf("invalid log file mode %v: permission bits with owner write expected", fileMode)
+	}
+
+	if fname == "" {
+		return fmt.Errorf("empty log file name")
+	}
+	if fi, err := os.Stat(fname); err == nil && fi.IsDir() {
+		return fmt.Errorf("log path %q is a directory", fname)
+	}
+	return nil
+}
+
+// openLogFile opens fname for appending, the file is created if it does not exist.
+func openLogFile(fname string, fileMode os.FileMode, sync bool) (*os.File, error) {
+	flag := os.O_CREATE | os.O_WRONLY | os.O_APPEND
+	if sync {
+		flag |= os.O_SYNC
+	}
+	return os.OpenFile(fname, flag, fileMode)
+}
+
+// OpenGzipFile opens log file like OpenFile and returns writer compressing data by gzip.
+// Close flushes the compressed stream and closes the file, it must be called to get a valid gzip file.
+// Flush (called by Logger.Sync) writes pending compressed data.
+//
+// When the file already exists a new gzip stream is appended to it.
+// Such multi-stream file is valid, gunzip and gzip.Reader read all streams as one.
+func OpenGzipFile(fname string) (io.WriteCloser, error) {
+	fd, err := OpenFile(fname)
+	if err != nil {
+		return nil, err
+	}
+
+	return &gzipFile{Writer: gzip.NewWriter(fd), fd: fd}, nil
+}
+
+// gzipFile is gzip.Writer closing the underlying file too.
+type gzipFile struct {
+	*gzip.Writer
+	fd *os.File
+}
+
+func (g *gzipFile) Close() error {
+	err := g.Writer.Close()
+	if errf := g.fd.Close(); err == nil {
+		err = errf
+	}
+	return err
+}
@@ -0,0 +1,331 @@
+package clog
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestOpenFileWithOptions(t *testing.T) {
+	tmp, err := ioutil.TempDir("", "clog-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmp)
+
+	dir := filepath.Join(tmp, "private")
+	fname := filepath.Join(dir, "app.log")
+	fd, err := OpenFileWithOptions(fname, 0600, 0700, true)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer fd.Close()
+
+	fi, err := os.Stat(fname)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fi.Mode().Perm() != 0600 {
+		t.Errorf("file mode expected: %v, got: %v", os.FileMode(0600), fi.Mode().Perm())
+	}
+
+	di, err := os.Stat(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if di.Mode().Perm() != 0700 {
+		t.Errorf("directory mode expected: %v, got: %v", os.FileMode(0700), di.Mode().Perm())
+	}
+
+	if _, err := fd.WriteString("line\n"); err != nil {
+		t.Errorf("unexpected write error: %v", err)
+	}
+}
+
+func TestOpenFileWithOptionsInvalidMode(t *testing.T) {
+	tests := []struct {
+		fileMode os.FileMode
+		dirMode  os.FileMode
+	}{
+		{0400, 0700},              // read-only file
+		{0600, 0600},              // directory not accessible
+		{os.ModeDir | 0600, 0700}, // not only permission bits
+		{0600, os.ModeSticky | 0700},
+	}
+
+	for _, tt := range tests {
+		fname := filepath.Join(os.TempDir(), "clog-test-never-created", "app.log")
+		if fd, err := OpenFileWithOptions(fname, tt.fileMode, tt.dirMode, false); err == nil {
+			fd.Close()
+			t.Errorf("modes %v, %v: expected error, got nil", tt.fileMode, tt.dirMode)
+		}
+	}
+}
+
+func TestOpenFileDirectory(t *testing.T) {
+	tmp, err := ioutil.TempDir("", "clog-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmp)
+
+	fd, err := OpenFile(tmp)
+	if err == nil {
+		fd.Close()
+		t.Fatal("expected error for directory, got nil")
+	}
+	if !strings.Contains(err.Error(), "is a directory") {
+		t.Errorf("expected clear directory error, got: %v", err)
+	}
+
+	if _, err := OpenFile(""); err == nil {
+		t.Error("expected error for empty file name, got nil")
+	}
+}
+
+func TestOpenFileBareName(t *testing.T) {
+	tmp, err := ioutil.TempDir("", "clog-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmp)
+
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(tmp); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+
+	fd, err := OpenFile("app.log")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	fd.Close()
+
+	if _, err := os.Stat(filepath.Join(tmp, "app.log")); err != nil {
+		t.Errorf("log file not created in working directory: %v", err)
+	}
+}
+
+func TestOpenFileNoMkdir(t *testing.T) {
+	tmp, err := ioutil.TempDir("", "clog-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmp)
+
+	dir := filepath.Join(tmp, "missing")
+	fd, err := OpenFileNoMkdir(filepath.Join(dir, "app.log"), 0600)
+	if err == nil {
+		fd.Close()
+		t.Error("expected error for missing directory, got nil")
+	}
+	if _, err := os.Stat(dir); !os.IsNotExist(err) {
+		t.Errorf("expected directory not created, got: %v", err)
+	}
+
+	fname := filepath.Join(tmp, "app.log")
+	for i := 0; i < 2; i++ {
+		fd, err = OpenFileNoMkdir(fname, 0600)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		fmt.Fprintf(fd, "line %d\n", i)
+		fd.Close()
+	}
+	b, err := ioutil.ReadFile(fname)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := "line 0\nline 1\n"; string(b) != expected {
+		t.Errorf("expected appended lines %q, got: %q", expected, b)
+	}
+}
+
+func TestNewWithFallback(t *testing.T) {
+	tmp, err := ioutil.TempDir("", "clog-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmp)
+
+	// parent is a regular file, so the log can't be opened even by root
+	blocker := filepath.Join(tmp, "file")
+	if err := ioutil.WriteFile(blocker, nil, 0600); err != nil {
+		t.Fatal(err)
+	}
+	fname := filepath.Join(blocker, "app.log")
+	stdout, stderr := captureConsole(t, func() {
+		l := NewWithFallback(fname, "info", true, WithFlags(0))
+		l.Info("still logging")
+		l.Debug("suppressed")
+	})
+	if stdout != "" {
+		t.Errorf("expected nothing on stdout, got: %q", stdout)
+	}
+	lines := strings.Split(strings.TrimSuffix(stderr, "\n"), "\n")
+	if len(lines) != 2 || !strings.HasPrefix(lines[0], "WARN:  log file ") || !strings.Contains(lines[0], fname) ||
+		lines[1] != "INFO:  still logging" {
+		t.Errorf("expected fallback warning and message on stderr, got: %q", stderr)
+	}
+
+	// options of the caller are not modified
+	opts := make([]Option, 1, 2)
+	opts[0] = WithFlags(0)
+	captureConsole(t, func() { NewWithFallback(fname, "info", false, opts...) })
+	if opts[:2][1] != nil {
+		t.Error("expected spare capacity of caller's options untouched")
+	}
+
+	// file is used if it can be opened, invalid level falls back to info
+	fname = filepath.Join(tmp, "app.log")
+	l := NewWithFallback(fname, "loud", false, WithConsole(false), WithFlags(0))
+	l.Info("in file")
+	l.Debug("suppressed")
+	if err := l.Close(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	b, err := ioutil.ReadFile(fname)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(string(b), "WARN:  \"loud\" is not valid log level") || !strings.HasSuffix(string(b), "INFO:  in file\n") {
+		t.Errorf("expected level warning and message in file, got: %q", b)
+	}
+}
+
+func TestOpenGzipFile(t *testing.T) {
+	tmp, err := ioutil.TempDir("", "clog-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmp)
+
+	fname := filepath.Join(tmp, "app.log.gz")
+	var expected []string
+	for run := 0; run < 2; run++ { // second run appends new gzip stream
+		gz, err := OpenGzipFile(fname)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		l, err := New(gz, "info", false, WithClock(testNow))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		for i := 0; i < 3; i++ {
+			l.Infof("run %d line %d", run, i)
+			expected = append(expected, fmt.Sprintf("INFO:  2017/03/09 14:05:07 run %d line %d", run, i))
+		}
+		if err := gz.Close(); err != nil {
+			t.Fatalf("unexpected close error: %v", err)
+		}
+	}
+
+	fd, err := os.Open(fname)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fd.Close()
+	zr, err := gzip.NewReader(fd)
+	if err != nil {
+		t.Fatalf("not a gzip file: %v", err)
+	}
+	data, err := ioutil.ReadAll(zr)
+	if err != nil {
+		t.Fatalf("gunzip error: %v", err)
+	}
+
+	got := strings.TrimSpace(string(data))
+	if got != strings.Join(expected, "\n") {
+		t.Errorf("expected:\n%s\ngot:\n%s", strings.Join(expected, "\n"), got)
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	tmp, err := ioutil.TempDir("", "clog-test")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer os.RemoveAll(tmp)
+
+	f, err := OpenFile(filepath.Join(tmp, "app.log"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer f.Close()
+
+	l, err := New(f, "info", false, WithClock(testNow), WithConsole(false))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	l.Info("before 1")
+	l.Info("before 2")
+	if err := l.Truncate(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	l.Info("after")
+
+	b, err := ioutil.ReadFile(f.Name())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if expected := "INFO:  2017/03/09 14:05:07 after\n"; string(b) != expected {
+		t.Errorf("expected: %q, got: %q", expected, b)
+	}
+
+	l.SetOutput(&bytes.Buffer{})
+	if err := l.Truncate(); err == nil {
+		t.Error("expected error of non-file writer")
+	}
+}
+
+func TestTruncateBatch(t *testing.T) {
+	tmp, err := ioutil.TempDir("", "clog-test")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer os.RemoveAll(tmp)
+
+	f, err := OpenFile(filepath.Join(tmp, "app.log"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer f.Close()
+
+	for _, opts := range [][]Option{
+		{WithBatch(4096, time.Hour)},
+		{WithBatch(4096, time.Hour), WithQueue(16, QueueBlock)},
+	} {
+		opts = append(opts, WithClock(testNow), WithConsole(false))
+		l, err := New(f, "info", false, opts...)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		l.Info("before 1")
+		l.Info("before 2")
+		if err := l.Truncate(); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		l.Info("after")
+		if err := l.Sync(); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		b, err := ioutil.ReadFile(f.Name())
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if expected := "INFO:  2017/03/09 14:05:07 after\n"; string(b) != expected {
+			t.Errorf("expected: %q, got: %q", expected, b)
+		}
+	}
+}
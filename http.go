@@ -0,0 +1,116 @@
+//
+// Copyright 2017 Radovan Vrždiak. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package clog
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+)
+
+// LevelHandler returns HTTP handler viewing and changing level of l at runtime,
+// typically mounted at /debug/loglevel:
+//
+//	GET                    returns current level, e.g. "info"
+//	PUT or POST ?level=x   sets level x (see SetLevel)
+//	PUT or POST with body  sets level given as text ("debug") or JSON ({"level":"debug"})
+//
+// Responses are plain text, JSON ({"level":"info"}) if request accepts application/json
+// or has JSON body. Invalid levels are rejected with 400 Bad Request.
+// The handler does no authorization, it should not be exposed publicly.
+func LevelHandler(l Logger) http.Handler {
+	return levelHandler{l: l}
+}
+
+type levelHandler struct {
+	l Logger
+}
+
+// levelBodyLimit limits size of request body with level.
+const levelBodyLimit = 1 << 10
+
+func (h levelHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	asJSON := strings.Contains(r.Header.Get("Accept"), "application/json") ||
+		strings.HasPrefix(r.Header.Get("Content-Type"), "application/json")
+
+	switch r.Method {
+	case http.MethodGet, http.MethodHead:
+	case http.MethodPut, http.MethodPost:
+		lv, err := requestLevel(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err := h.l.SetLevel(lv); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	default:
+		w.Header().Set("Allow", "GET, HEAD, PUT, POST")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	lv := currentLevel(h.l)
+	if asJSON {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, "{\"level\":%q}\n", lv)
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	fmt.Fprintln(w, lv)
+}
+
+// requestLevel returns level given by query parameter or body of r.
+func requestLevel(r *http.Request) (Level, error) {
+	if s := r.URL.Query().Get("level"); s != "" {
+		return parseLevel(s)
+	}
+
+	body, err := ioutil.ReadAll(io.LimitReader(r.Body, levelBodyLimit))
+	if err != nil {
+		return InvalidLevel, err
+	}
+	s := strings.TrimSpace(string(body))
+	if !strings.HasPrefix(s, "{") {
+		return parseLevel(s)
+	}
+
+	var req struct {
+		Level Level `json:"level"`
+	}
+	if err := json.Unmarshal([]byte(s), &req); err != nil {
+		return InvalidLevel, err
+	}
+	return req.Level, req.Level.Validate()
+}
+
+// parseLevel returns level named by s, empty s is rejected.
+func parseLevel(s string) (Level, error) {
+	lv, err := LevelFromString(s)
+	if err != nil {
+		return InvalidLevel, err
+	}
+	return lv, lv.Validate()
+}
+
+// currentLevel returns level of l, the most verbose active level for loggers of other packages.
+func currentLevel(l Logger) Level {
+	if x := internal(l); x != nil && x.level != nil {
+		return x.level.get()
+	}
+
+	lv := InvalidLevel
+	for _, x := range l.ActiveLevels() {
+		if x > lv {
+			lv = x
+		}
+	}
+	return lv
+}
@@ -0,0 +1,65 @@
+package clog
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestLevelHandler(t *testing.T) {
+	l, _ := NewTestLogger(InfoLevel)
+	srv := httptest.NewServer(LevelHandler(l))
+	defer srv.Close()
+
+	do := func(method, query, contentType, body string) (int, string) {
+		req, err := http.NewRequest(method, srv.URL+query, strings.NewReader(body))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if contentType != "" {
+			req.Header.Set("Content-Type", contentType)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		defer resp.Body.Close()
+		b, err := ioutil.ReadAll(resp.Body)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		return resp.StatusCode, string(b)
+	}
+
+	tests := []struct {
+		method, query, contentType, body string
+		code                             int
+		resp                             string
+		level                            Level
+	}{
+		{"GET", "", "", "", http.StatusOK, "info\n", InfoLevel},
+		{"PUT", "", "text/plain", "debug\n", http.StatusOK, "debug\n", DebugLevel},
+		{"POST", "?level=warning", "", "", http.StatusOK, "warning\n", WarnLevel},
+		{"PUT", "", "application/json", `{"level":"error"}`, http.StatusOK, `{"level":"error"}` + "\n", ErrorLevel},
+		{"PUT", "", "text/plain", "verbose", http.StatusBadRequest, "", ErrorLevel},
+		{"PUT", "?level=loud", "", "", http.StatusBadRequest, "", ErrorLevel},
+		{"PUT", "", "application/json", `{"level":0}`, http.StatusBadRequest, "", ErrorLevel},
+		{"PUT", "", "text/plain", "", http.StatusBadRequest, "", ErrorLevel},
+		{"POST", "", "text/plain", " \n\t", http.StatusBadRequest, "", ErrorLevel},
+		{"DELETE", "", "", "", http.StatusMethodNotAllowed, "", ErrorLevel},
+	}
+	for _, tt := range tests {
+		code, resp := do(tt.method, tt.query, tt.contentType, tt.body)
+		if code != tt.code {
+			t.Errorf("%s %q expected status %d, got %d: %q", tt.method, tt.body+tt.query, tt.code, code, resp)
+		}
+		if tt.resp != "" && resp != tt.resp {
+			t.Errorf("%s %q expected: %q, got: %q", tt.method, tt.body+tt.query, tt.resp, resp)
+		}
+		if lv := internal(l).level.get(); lv != tt.level {
+			t.Errorf("%s %q expected level %s, got %s", tt.method, tt.body+tt.query, tt.level, lv)
+		}
+	}
+}
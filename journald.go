@@ -0,0 +1,104 @@
+//
+// Copyright 2017 Radovan Vrždiak. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build linux
+// +build linux
+
+package clog
+
+import (
+	"bytes"
+	"encoding/binary"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// journalSocket is the socket of systemd journal native protocol.
+const journalSocket = "/run/systemd/journal/socket"
+
+// NewJournald creates new Logger like New writing to systemd journal using its native protocol,
+// level of messages is sent as PRIORITY so journalctl shows proper severity.
+// Journal adds timestamp itself, so entries have neither timestamp nor level prefix
+// unless opts (e.g. WithFlags) say otherwise. Console mirror can be disabled by WithConsole(false).
+// It returns nil Logger and error if level is not valid or journal socket is not available.
+func NewJournald(level string, verbose bool, opts ...Option) (Logger, error) {
+	return newJournald(journalSocket, level, verbose, opts)
+}
+
+// newJournald creates Logger writing to journal socket at path.
+func newJournald(path, level string, verbose bool, opts []Option) (Logger, error) {
+	if _, err := LevelFromString(level); err != nil {
+		return nil, err
+	}
+
+	w, err := newJournalWriter(path)
+	if err != nil {
+		return nil, err
+	}
+
+	opts = append([]Option{WithFlags(0), WithoutStorageLevelPrefix()}, opts...)
+	return New(w, level, verbose, opts...)
+}
+
+// journalWriter sends every entry as one datagram in journal native protocol.
+type journalWriter struct {
+	conn  *net.UnixConn
+	ident string // SYSLOG_IDENTIFIER
+}
+
+func newJournalWriter(path string) (*journalWriter, error) {
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: path, Net: "unixgram"})
+	if err != nil {
+		return nil, err
+	}
+	return &journalWriter{conn: conn, ident: filepath.Base(os.Args[0])}, nil
+}
+
+// Write sends p as info message.
+func (j *journalWriter) Write(p []byte) (int, error) {
+	return j.WriteLevel(InfoLevel, p)
+}
+
+// WriteLevel sends p with priority of lv, trailing newline is removed.
+func (j *journalWriter) WriteLevel(lv Level, p []byte) (int, error) {
+	b := getBuffer()
+	defer putBuffer(b)
+
+	*b = appendJournalField(*b, "PRIORITY", strconv.Itoa(syslogSeverity(lv)))
+	*b = appendJournalField(*b, "SYSLOG_IDENTIFIER", j.ident)
+	*b = appendJournalField(*b, "MESSAGE", string(bytes.TrimSuffix(p, []byte("\n"))))
+
+	if _, err := j.conn.Write(*b); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// Close closes connection to journal.
+func (j *journalWriter) Close() error {
+	return j.conn.Close()
+}
+
+// appendJournalField appends field framed according to journal native protocol:
+// KEY=value followed by newline, or for values containing newline
+// KEY, newline, little endian 64-bit length, value and newline.
+func appendJournalField(b []byte, key, value string) []byte {
+	b = append(b, key...)
+	if !strings.Contains(value, "\n") {
+		b = append(b, '=')
+		b = append(b, value...)
+		return append(b, '\n')
+	}
+
+	b = append(b, '\n')
+	var n [8]byte
+	binary.LittleEndian.PutUint64(n[:], uint64(len(value)))
+	b = append(b, n[:]...)
+	b = append(b, value...)
+	return append(b, '\n')
+}
@@ -0,0 +1,89 @@
+//go:build linux
+// +build linux
+
+package clog
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// parseJournal parses fields framed according to journal native protocol.
+func parseJournal(t *testing.T, b []byte) map[string]string {
+	t.Helper()
+
+	fields := map[string]string{}
+	for len(b) > 0 {
+		i := bytes.IndexAny(b, "=\n")
+		if i < 0 {
+			t.Fatalf("invalid datagram: %q", b)
+		}
+		key := string(b[:i])
+		if b[i] == '=' {
+			end := bytes.IndexByte(b, '\n')
+			fields[key] = string(b[i+1 : end])
+			b = b[end+1:]
+			continue
+		}
+
+		n := int(binary.LittleEndian.Uint64(b[i+1 : i+9]))
+		fields[key] = string(b[i+9 : i+9+n])
+		b = b[i+9+n+1:]
+	}
+	return fields
+}
+
+func TestJournald(t *testing.T) {
+	dir, err := ioutil.TempDir("", "clog")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "socket")
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: path, Net: "unixgram"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer conn.Close()
+
+	l, err := newJournald(path, "info", false, []Option{WithConsole(false)})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	l.Warn("disk almost full")
+	l.Error("first line\nsecond line")
+	l.Debug("hidden")
+	l.Info("done")
+
+	expected := []map[string]string{
+		{"PRIORITY": "4", "MESSAGE": "disk almost full"},
+		{"PRIORITY": "3", "MESSAGE": "first line\nsecond line"},
+		{"PRIORITY": "6", "MESSAGE": "done"},
+	}
+	buf := make([]byte, 4096)
+	for _, e := range expected {
+		n, err := conn.Read(buf)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		got := parseJournal(t, buf[:n])
+		for k, v := range e {
+			if got[k] != v {
+				t.Errorf("%s expected: %q, got: %q", k, v, got[k])
+			}
+		}
+		if got["SYSLOG_IDENTIFIER"] == "" {
+			t.Errorf("expected SYSLOG_IDENTIFIER, got: %q", got)
+		}
+	}
+
+	if _, err := newJournald(path, "invalid", false, nil); err == nil {
+		t.Error("expected error of invalid level")
+	}
+}
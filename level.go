@@ -0,0 +1,291 @@
+//
+// Copyright 2017 Radovan Vrždiak. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package clog
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync/atomic"
+)
+
+// AllLevels returns all valid levels (without InvalidLevel) ordered from DisabledLevel to DebugLevel.
+func AllLevels() []Level {
+	levels := make([]Level, 0, len(logLevels))
+	for l := range logLevels {
+		if l != InvalidLevel {
+			levels = append(levels, l)
+		}
+	}
+	sort.Slice(levels, func(i, j int) bool { return levels[i] < levels[j] })
+
+	return levels
+}
+
+// Enabled reports whether message of level l is written by logger at threshold level,
+// e.g. WarnLevel.Enabled(InfoLevel) is true and DebugLevel.Enabled(InfoLevel) is false.
+// DisabledLevel stands for fatal messages which are written at every threshold.
+// It is false if l or threshold is not valid.
+func (l Level) Enabled(threshold Level) bool {
+	return enabledAt(l, threshold)
+}
+
+// enabledAt implements Level.Enabled without map lookups, valid levels are DisabledLevel to DebugLevel.
+func enabledAt(lv, threshold Level) bool {
+	return DisabledLevel <= lv && lv <= threshold && threshold <= DebugLevel
+}
+
+// LevelStrings returns names of all valid levels in the order of AllLevels,
+// i.e. "disabled", "error", "warning", "info", "debug".
+func LevelStrings() []string {
+	levels := AllLevels()
+	names := make([]string, 0, len(levels))
+	for _, l := range levels {
+		names = append(names, l.String())
+	}
+
+	return names
+}
+
+// MarshalJSON implements json.Marshaler. Level is encoded as its string form, e.g. "debug".
+func (l Level) MarshalJSON() ([]byte, error) {
+	if err := l.Validate(); err != nil {
+		return nil, err
+	}
+	return json.Marshal(l.String())
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+// It accepts string form (see LevelFromString) as well as numeric value of Level.
+// JSON null leaves Level unchanged.
+func (l *Level) UnmarshalJSON(data []byte) error {
+	data = bytes.TrimSpace(data)
+	if len(data) == 0 {
+		return fmt.Errorf("log level: empty JSON value")
+	}
+
+	var lv Level
+	switch c := data[0]; {
+	case bytes.Equal(data, []byte("null")):
+		return nil
+	case c == '"':
+		var s string
+		if err := json.Unmarshal(data, &s); err != nil {
+			return err
+		}
+		x, err := LevelFromString(s)
+		if err != nil {
+			return err
+		}
+		lv = x
+	case c == '-' || (c >= '0' && c <= '9'):
+		var n int
+		if err := json.Unmarshal(data, &n); err != nil {
+			return fmt.Errorf("log level: %v", err)
+		}
+		lv = Level(n)
+	default:
+		return fmt.Errorf("log level must be JSON string or number, got: %s", data)
+	}
+
+	if err := lv.Validate(); err != nil {
+		return err
+	}
+	*l = lv
+
+	return nil
+}
+
+// LevelFromEnv returns level given by environment variable key (see LevelFromString),
+// e.g. LevelFromEnv("LOG_LEVEL", InfoLevel) for LOG_LEVEL=debug.
+// fallback is returned if the variable is not set or empty.
+// It is returned for invalid value too and warning about the value is written to stderr.
+func LevelFromEnv(key string, fallback Level) Level {
+	s := strings.TrimSpace(os.Getenv(key))
+	if s == "" {
+		return fallback
+	}
+
+	lv, err := LevelFromString(s)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "WARN:  %s: %v; using %s\n", key, err, fallback)
+		return fallback
+	}
+	return lv
+}
+
+// SetLevel changes level of the whole logger tree: l, its parent and sibling loggers
+// and all child loggers (see Named) share one level, use SetLevelFor to change level of a named logger only
+// and Clone for independent level. It is safe to call concurrently with logging.
+// Logger created at DisabledLevel is optimized to no-op implementation
+// and its level can't be changed, error is returned for it as well as for invalid level.
+func (l *logger) SetLevel(level Level) error {
+	if err := level.Validate(); err != nil {
+		return err
+	}
+	if l == nil || l.fatal == nil {
+		return fmt.Errorf("logger is not initialized")
+	}
+
+	l.level.set(level)
+	return nil
+}
+
+// WithLevel sets level of l (see SetLevel) until restore is called,
+// typically to raise verbosity for a scope:
+//
+//	defer l.WithLevel(clog.DebugLevel)()
+//
+// restore sets the level captured when WithLevel was called,
+// changes made meanwhile (e.g. by SetLevel in other goroutine) are overwritten.
+// If level can't be set, restore does nothing.
+func (l *logger) WithLevel(level Level) (restore func()) {
+	if l == nil || l.level == nil {
+		return func() {}
+	}
+
+	prev := l.level.get()
+	if l.SetLevel(level) != nil {
+		return func() {}
+	}
+	return func() { l.level.set(prev) }
+}
+
+// ActiveLevels returns levels of messages written by l ordered like AllLevels,
+// DisabledLevel stands for fatal messages which are always written,
+// e.g. DisabledLevel, ErrorLevel, WarnLevel and InfoLevel for logger at InfoLevel.
+// It reflects current level (see SetLevel and SetLevelFor).
+func (l *logger) ActiveLevels() []Level {
+	if l == nil || l.fatal == nil {
+		return nil
+	}
+
+	levels := []Level{DisabledLevel}
+	for _, lv := range AllLevels() {
+		if l.IsEnabled(lv) {
+			levels = append(levels, lv)
+		}
+	}
+	return levels
+}
+
+// syslogSeverity returns syslog severity of lv, DisabledLevel stands for fatal messages.
+func syslogSeverity(lv Level) int {
+	switch lv {
+	case DisabledLevel:
+		return 2 // crit
+	case ErrorLevel:
+		return 3 // err
+	case WarnLevel:
+		return 4 // warning
+	case DebugLevel:
+		return 7 // debug
+	}
+	return 6 // info
+}
+
+// levelVar holds Level of logger and its children, it is safe for concurrent use.
+// Level is read atomically without lock, it is checked by every logging call.
+type levelVar struct {
+	lv      int32
+	initial Level // restored by reset
+	// bit masks of levels overriding lv, see EnableLevel and DisableLevel
+	enabled  uint32
+	disabled uint32
+}
+
+func newLevelVar(lv Level) *levelVar {
+	return &levelVar{lv: int32(lv), initial: lv}
+}
+
+// get returns level, InvalidLevel for nil v.
+func (v *levelVar) get() Level {
+	if v == nil {
+		return InvalidLevel
+	}
+	return Level(atomic.LoadInt32(&v.lv))
+}
+
+func (v *levelVar) set(lv Level) {
+	atomic.StoreInt32(&v.lv, int32(lv))
+}
+
+// reset restores level given to newLevelVar and removes overrides.
+func (v *levelVar) reset() {
+	v.set(v.initial)
+	atomic.StoreUint32(&v.enabled, 0)
+	atomic.StoreUint32(&v.disabled, 0)
+}
+
+// override returns whether lv is enabled (true, true) or disabled (false, true) explicitly.
+func (v *levelVar) override(lv Level) (enabled, ok bool) {
+	if v == nil {
+		return false, false
+	}
+	bit := uint32(1) << uint(lv)
+	if atomic.LoadUint32(&v.disabled)&bit != 0 {
+		return false, true
+	}
+	if atomic.LoadUint32(&v.enabled)&bit != 0 {
+		return true, true
+	}
+	return false, false
+}
+
+// setOverride sets explicit enablement of lv.
+func (v *levelVar) setOverride(lv Level, enabled bool) {
+	bit := uint32(1) << uint(lv)
+	set, clear := &v.enabled, &v.disabled
+	if !enabled {
+		set, clear = clear, set
+	}
+	for {
+		old := atomic.LoadUint32(clear)
+		if atomic.CompareAndSwapUint32(clear, old, old&^bit) {
+			break
+		}
+	}
+	for {
+		old := atomic.LoadUint32(set)
+		if atomic.CompareAndSwapUint32(set, old, old|bit) {
+			break
+		}
+	}
+}
+
+// EnableLevel enables messages of level regardless of level of the log (see SetLevel),
+// e.g. error and debug messages without warnings and info messages together with DisableLevel.
+// It overrides previous DisableLevel of level, threshold still decides about other levels.
+// Overrides are shared with Named children and they are removed by Reset.
+// Only ErrorLevel, WarnLevel, InfoLevel and DebugLevel are accepted, other levels are ignored.
+func (l *logger) EnableLevel(level Level) {
+	if l == nil || l.level == nil || level < ErrorLevel || level > DebugLevel {
+		return
+	}
+	l.level.setOverride(level, true)
+}
+
+// DisableLevel disables messages of level regardless of level of the log (see SetLevel),
+// e.g. info and error messages without warnings of noisy dependency.
+// It overrides previous EnableLevel of level. Fatal messages can't be disabled, see EnableLevel.
+func (l *logger) DisableLevel(level Level) {
+	if l == nil || l.level == nil || level < ErrorLevel || level > DebugLevel {
+		return
+	}
+	l.level.setOverride(level, false)
+}
+
+// levelEnabled reports whether messages of lv pass level of l including overrides of EnableLevel
+// and DisableLevel. Outputs are not checked.
+func (l *logger) levelEnabled(lv Level) bool {
+	if enabled, ok := l.level.override(lv); ok {
+		return enabled
+	}
+	return enabledAt(lv, l.effectiveLevel())
+}
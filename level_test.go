@@ -0,0 +1,407 @@
+package clog
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestLevelJSONRoundTrip(t *testing.T) {
+	type config struct {
+		Level Level `json:"level"`
+	}
+
+	for l := DisabledLevel; l <= DebugLevel; l++ {
+		data, err := json.Marshal(config{Level: l})
+		if err != nil {
+			t.Fatalf("%q: unexpected error: %v", l, err)
+		}
+		expected := fmt.Sprintf(`{"level":%q}`, l.String())
+		if string(data) != expected {
+			t.Errorf("expected: %s, got: %s", expected, data)
+		}
+
+		// string form
+		var c config
+		if err := json.Unmarshal(data, &c); err != nil {
+			t.Errorf("%s: unexpected error: %v", data, err)
+		}
+		if c.Level != l {
+			t.Errorf("%s: expected: %d, got: %d", data, l, c.Level)
+		}
+
+		// numeric form
+		c = config{}
+		num := fmt.Sprintf(`{"level": %d}`, l)
+		if err := json.Unmarshal([]byte(num), &c); err != nil {
+			t.Errorf("%s: unexpected error: %v", num, err)
+		}
+		if c.Level != l {
+			t.Errorf("%s: expected: %d, got: %d", num, l, c.Level)
+		}
+	}
+}
+
+func TestLevelJSONInvalid(t *testing.T) {
+	for _, l := range []Level{InvalidLevel, Level(42)} {
+		if _, err := json.Marshal(l); err == nil {
+			t.Errorf("marshal %d: expected error, got nil", l)
+		}
+	}
+
+	inputs := []string{`"nonsense"`, `""`, `42`, `0`, `1.5`, `{}`, `["info"]`, `true`}
+	for _, in := range inputs {
+		l := InfoLevel
+		if err := json.Unmarshal([]byte(in), &l); err == nil {
+			t.Errorf("unmarshal %s: expected error, got nil", in)
+		}
+		if l != InfoLevel {
+			t.Errorf("unmarshal %s: level should stay unchanged, got %d", in, l)
+		}
+	}
+
+	l := WarnLevel
+	if err := json.Unmarshal([]byte(`null`), &l); err != nil || l != WarnLevel {
+		t.Errorf("unmarshal null: expected unchanged level without error, got %d, %v", l, err)
+	}
+}
+
+func TestAllLevels(t *testing.T) {
+	levels := AllLevels()
+	names := LevelStrings()
+	if len(levels) != len(testValidMap)-1 || len(names) != len(levels) {
+		t.Fatalf("expected %d levels, got %d levels and %d names", len(testValidMap)-1, len(levels), len(names))
+	}
+
+	for i, l := range levels {
+		if i > 0 && levels[i-1] >= l {
+			t.Errorf("levels not ordered: %v", levels)
+		}
+		if names[i] != testValidMap[l] {
+			t.Errorf("name of %d expected: %q, got: %q", l, testValidMap[l], names[i])
+		}
+		lx, err := LevelFromString(names[i])
+		if err != nil || lx != l {
+			t.Errorf("LevelFromString(%q) expected: %d, got: %d, %v", names[i], l, lx, err)
+		}
+	}
+
+	expected := "disabled error warning info debug"
+	if got := fmt.Sprint(names); got != "["+expected+"]" {
+		t.Errorf("expected: [%s], got: %s", expected, got)
+	}
+}
+
+func TestEnableDisableLevel(t *testing.T) {
+	buf := &bytes.Buffer{}
+	l, err := New(buf, "info", false, WithClock(testNow), WithConsole(false))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	child := l.Named("dep")
+	l.DisableLevel(WarnLevel)
+
+	l.Info("info")
+	l.Warn("warn")
+	child.Warnf("noisy %d", 1)
+	l.Error("error")
+	expected := "INFO:  2017/03/09 14:05:07 info\n" +
+		"ERROR: 2017/03/09 14:05:07 error\n"
+	if buf.String() != expected {
+		t.Errorf("expected: %q, got: %q", expected, buf.String())
+	}
+	if l.IsEnabled(WarnLevel) || fmt.Sprint(l.ActiveLevels()) != "[disabled error info]" {
+		t.Errorf("expected warnings disabled, got: %v", l.ActiveLevels())
+	}
+
+	// overrides survive threshold changes, enable overrides disable
+	buf.Reset()
+	if err := l.SetLevel(ErrorLevel); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	l.EnableLevel(DebugLevel)
+	l.EnableLevel(WarnLevel)
+	l.DisableLevel(DisabledLevel) // ignored
+	l.Debugw("debug")
+	l.Info("suppressed")
+	l.Warn("warn")
+	if out := buf.String(); !strings.HasPrefix(out, "DEBUG: ") || !strings.HasSuffix(out, " debug\nWARN:  2017/03/09 14:05:07 warn\n") {
+		t.Errorf("expected debug and warning, got: %q", out)
+	}
+
+	l.Reset()
+	if fmt.Sprint(l.ActiveLevels()) != "[disabled error warning info]" {
+		t.Errorf("expected overrides removed by Reset, got: %v", l.ActiveLevels())
+	}
+}
+
+func TestFatalLevel(t *testing.T) {
+	if FatalLevel != DisabledLevel || FatalLevel.Validate() != nil || AllLevels()[0] != FatalLevel {
+		t.Fatalf("expected valid FatalLevel equal to DisabledLevel and first in AllLevels")
+	}
+	for _, s := range []string{"fatal", " FATAL ", "disabled"} {
+		if lv, err := LevelFromString(s); err != nil || lv != FatalLevel {
+			t.Errorf("LevelFromString(%q) expected: %d, got: %d, %v", s, FatalLevel, lv, err)
+		}
+	}
+
+	// JSON round-trip
+	var lv Level
+	if err := json.Unmarshal([]byte(`"fatal"`), &lv); err != nil || lv != FatalLevel {
+		t.Errorf("expected FatalLevel, got: %d, %v", lv, err)
+	}
+	data, err := json.Marshal(FatalLevel)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	lv = InvalidLevel
+	if err := json.Unmarshal(data, &lv); err != nil || lv != FatalLevel {
+		t.Errorf("%s: expected FatalLevel, got: %d, %v", data, lv, err)
+	}
+
+	// fatal messages are written at every level, nothing else at FatalLevel
+	buf := &bytes.Buffer{}
+	l, err := New(buf, "fatal", false, WithClock(testNow), WithConsole(false), WithExitFunc(func(int) {}))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	l.Error("suppressed")
+	l.Fatal("written")
+	if expected := "FATAL: 2017/03/09 14:05:07 written\n"; buf.String() != expected {
+		t.Errorf("expected: %q, got: %q", expected, buf.String())
+	}
+	for _, threshold := range AllLevels() {
+		if !FatalLevel.Enabled(threshold) {
+			t.Errorf("expected fatal messages enabled at %s", threshold)
+		}
+	}
+}
+
+func TestLevelEnabled(t *testing.T) {
+	// written[threshold] lists message levels written by logger at threshold
+	written := map[Level][]Level{
+		DisabledLevel: {DisabledLevel},
+		ErrorLevel:    {DisabledLevel, ErrorLevel},
+		WarnLevel:     {DisabledLevel, ErrorLevel, WarnLevel},
+		InfoLevel:     {DisabledLevel, ErrorLevel, WarnLevel, InfoLevel},
+		DebugLevel:    {DisabledLevel, ErrorLevel, WarnLevel, InfoLevel, DebugLevel},
+	}
+
+	levels := append([]Level{InvalidLevel, Level(99)}, AllLevels()...)
+	for _, threshold := range levels {
+		for _, msg := range levels {
+			expected := false
+			for _, lv := range written[threshold] {
+				expected = expected || lv == msg
+			}
+			if got := msg.Enabled(threshold); got != expected {
+				t.Errorf("%d.Enabled(%d) expected: %v, got: %v", msg, threshold, expected, got)
+			}
+		}
+	}
+
+	// consistent with logger
+	for _, threshold := range AllLevels() {
+		l, _ := NewTestLogger(threshold)
+		for _, msg := range []Level{ErrorLevel, WarnLevel, InfoLevel, DebugLevel} {
+			if msg.Enabled(threshold) != l.IsEnabled(msg) {
+				t.Errorf("%s.Enabled(%s) differs from IsEnabled", msg, threshold)
+			}
+		}
+	}
+}
+
+func TestSetLevel(t *testing.T) {
+	buf := &bytes.Buffer{}
+	l, err := New(buf, "warning", false, WithClock(testNow), WithCallerPath(ShortPath))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	child := l.Named("db")
+
+	l.Info("hidden")
+	if err := l.SetLevel(InfoLevel); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	l.Info("shown")
+	child.Info("child")
+	if err := child.SetLevel(ErrorLevel); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	l.Warn("hidden")
+
+	expected := "INFO:  2017/03/09 14:05:07 shown\nINFO:  2017/03/09 14:05:07 db: child\n"
+	if buf.String() != expected {
+		t.Errorf("expected: %q, got: %q", expected, buf.String())
+	}
+
+	if err := l.SetLevel(InvalidLevel); err == nil {
+		t.Error("invalid level: expected error, got nil")
+	}
+	if l.IsEnabled(WarnLevel) || !l.IsEnabled(ErrorLevel) {
+		t.Error("invalid level: level should stay ErrorLevel")
+	}
+
+	d, _ := New(buf, "disabled", false)
+	if err := d.SetLevel(DebugLevel); err == nil {
+		t.Error("disabled logger: expected error, got nil")
+	}
+}
+
+func TestSetLevelShared(t *testing.T) {
+	l, _ := NewTestLogger(InfoLevel)
+	db, http := l.Named("db"), l.Named("http")
+
+	if err := db.SetLevel(ErrorLevel); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for name, x := range map[string]Logger{"parent": l, "child": db, "sibling": http, "grandchild": db.Named("sql")} {
+		if x.IsEnabled(WarnLevel) || !x.IsEnabled(ErrorLevel) {
+			t.Errorf("%s: expected shared ErrorLevel", name)
+		}
+	}
+}
+
+func TestWithLevel(t *testing.T) {
+	buf := &bytes.Buffer{}
+	l, err := New(buf, "info", false, WithClock(testNow))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	func() {
+		defer func() { recover() }()
+		defer l.WithLevel(DebugLevel)()
+
+		if !l.IsEnabled(DebugLevel) {
+			t.Error("expected DebugLevel in scope")
+		}
+		panic("scope failed")
+	}()
+
+	if l.IsEnabled(DebugLevel) || !l.IsEnabled(InfoLevel) {
+		t.Error("expected InfoLevel restored after panic")
+	}
+
+	// restore wins over changes made in scope
+	restore := l.WithLevel(ErrorLevel)
+	l.SetLevel(WarnLevel)
+	restore()
+	if !l.IsEnabled(InfoLevel) {
+		t.Error("expected InfoLevel restored")
+	}
+
+	// restore of failed change does nothing
+	restore = l.WithLevel(Level(999))
+	l.SetLevel(WarnLevel)
+	restore()
+	if l.IsEnabled(InfoLevel) {
+		t.Error("expected WarnLevel kept")
+	}
+}
+
+func TestLevelFromEnv(t *testing.T) {
+	const key = "CLOG_TEST_LEVEL"
+	defer os.Unsetenv(key)
+
+	os.Unsetenv(key)
+	if got := LevelFromEnv(key, WarnLevel); got != WarnLevel {
+		t.Errorf("unset: expected: %v, got: %v", WarnLevel, got)
+	}
+
+	os.Setenv(key, " Debug ")
+	if got := LevelFromEnv(key, WarnLevel); got != DebugLevel {
+		t.Errorf("set: expected: %v, got: %v", DebugLevel, got)
+	}
+
+	os.Setenv(key, "")
+	if got := LevelFromEnv(key, InfoLevel); got != InfoLevel {
+		t.Errorf("empty: expected: %v, got: %v", InfoLevel, got)
+	}
+
+	os.Setenv(key, "verbose")
+	var got Level
+	_, stderr := captureConsole(t, func() {
+		got = LevelFromEnv(key, InfoLevel)
+	})
+	if got != InfoLevel {
+		t.Errorf("invalid: expected: %v, got: %v", InfoLevel, got)
+	}
+	if !strings.Contains(stderr, key) || !strings.Contains(stderr, `"verbose"`) {
+		t.Errorf("invalid: expected warning about %s, got: %q", key, stderr)
+	}
+}
+
+func TestActiveLevels(t *testing.T) {
+	tests := []struct {
+		level    Level
+		expected []Level
+	}{
+		{DisabledLevel, []Level{DisabledLevel}},
+		{ErrorLevel, []Level{DisabledLevel, ErrorLevel}},
+		{WarnLevel, []Level{DisabledLevel, ErrorLevel, WarnLevel}},
+		{InfoLevel, []Level{DisabledLevel, ErrorLevel, WarnLevel, InfoLevel}},
+		{DebugLevel, []Level{DisabledLevel, ErrorLevel, WarnLevel, InfoLevel, DebugLevel}},
+	}
+
+	for _, tt := range tests {
+		l, _ := NewTestLogger(tt.level)
+		if got := l.ActiveLevels(); fmt.Sprint(got) != fmt.Sprint(tt.expected) {
+			t.Errorf("%s: expected: %v, got: %v", tt.level, tt.expected, got)
+		}
+	}
+
+	l, _ := NewTestLogger(WarnLevel)
+	l.SetLevel(InfoLevel)
+	if got, expected := l.ActiveLevels(), []Level{DisabledLevel, ErrorLevel, WarnLevel, InfoLevel}; fmt.Sprint(got) != fmt.Sprint(expected) {
+		t.Errorf("after SetLevel expected: %v, got: %v", expected, got)
+	}
+}
+
+// lockedLevel is level guarded by lock, the former levelVar, for comparison in benchmarks.
+type lockedLevel struct {
+	mu sync.RWMutex
+	lv Level
+}
+
+func (v *lockedLevel) get() Level {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+	return v.lv
+}
+
+func BenchmarkLevelCheckLocked(b *testing.B) {
+	v := &lockedLevel{lv: InfoLevel}
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			if v.get() >= DebugLevel {
+				b.Fatal("unexpected level")
+			}
+		}
+	})
+}
+
+func BenchmarkLevelCheckAtomic(b *testing.B) {
+	v := newLevelVar(InfoLevel)
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			if v.get() >= DebugLevel {
+				b.Fatal("unexpected level")
+			}
+		}
+	})
+}
+
+func BenchmarkDebugDisabledParallel(b *testing.B) {
+	l := newBenchLogger(b, "info")
+	b.ReportAllocs()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			l.Debugf("request %s handled: %d", "/index", 200)
+		}
+	})
+}
@@ -0,0 +1,40 @@
+//
+// Copyright 2017 Radovan Vrždiak. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package clog
+
+// Named returns child logger adding name to every message, e.g. "db: connected".
+// Names of nested children are joined by dot: l.Named("db").Named("conn") gives "db.conn".
+// Children share writers, level and options with l. Empty name returns l.
+func (l *logger) Named(name string) Logger {
+	if l == nil || name == "" {
+		return l
+	}
+
+	child := l.child()
+	if l.name != "" {
+		name = l.name + "." + name
+	}
+	child.name = name
+
+	return &child
+}
+
+// WithPrefix returns child logger prepending prefix and space to every message,
+// e.g. hostname or application tag. Prefixes of nested children are joined by space.
+// Children share writers, level and options with l (see Named). Empty prefix returns l.
+func (l *logger) WithPrefix(prefix string) Logger {
+	if l == nil || prefix == "" {
+		return l
+	}
+
+	child := l.child()
+	if l.prefix != "" {
+		prefix = l.prefix + " " + prefix
+	}
+	child.prefix = prefix
+
+	return &child
+}
@@ -0,0 +1,71 @@
+package clog
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestNamed(t *testing.T) {
+	buf := &bytes.Buffer{}
+	root, err := New(buf, "info", false, WithClock(testNow))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	a := root.Named("a")
+	ab := a.Named("b")
+
+	root.Info("root")
+	a.Info("child")
+	ab.Infof("grand%s", "child")
+	a.Named("").Info("same")
+	a.StdLogger(InfoLevel).Print("std")
+
+	expected := []string{
+		"INFO:  2017/03/09 14:05:07 root",
+		"INFO:  2017/03/09 14:05:07 a: child",
+		"INFO:  2017/03/09 14:05:07 a.b: grandchild",
+		"INFO:  2017/03/09 14:05:07 a: same",
+		"INFO:  2017/03/09 14:05:07 a: std",
+	}
+	got := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if strings.Join(got, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("expected:\n%s\ngot:\n%s", strings.Join(expected, "\n"), buf.String())
+	}
+}
+
+func TestWithPrefix(t *testing.T) {
+	buf := &bytes.Buffer{}
+	root, err := New(buf, "info", false, WithClock(testNow), WithConsole(false))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	l := root.WithPrefix("host1").WithPrefix("api")
+	l.Info("started")
+	l.Named("db").Error("failed")
+	root.WithPrefix("").Info("plain")
+
+	expected := "INFO:  2017/03/09 14:05:07 host1 api started\n" +
+		"ERROR: 2017/03/09 14:05:07 host1 api db: failed\n" +
+		"INFO:  2017/03/09 14:05:07 plain\n"
+	if buf.String() != expected {
+		t.Errorf("expected: %q, got: %q", expected, buf.String())
+	}
+}
+
+func TestWithPrefixJSON(t *testing.T) {
+	buf := &bytes.Buffer{}
+	root, err := New(buf, "info", false, WithClock(testNow), WithConsole(false), WithFormat(JSONFormat))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	internal(root).flags = 0 // no time
+
+	root.WithPrefix("host1").Error("failed")
+	expected := `{"level":"error","prefix":"host1","msg":"failed"}` + "\n"
+	if buf.String() != expected {
+		t.Errorf("expected: %q, got: %q", expected, buf.String())
+	}
+}
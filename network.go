@@ -0,0 +1,243 @@
+//
+// Copyright 2017 Radovan Vrždiak. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package clog
+
+import (
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxDatagram limits size of UDP batches so datagrams fit into typical MTU without fragmentation.
+const maxDatagram = 1400
+
+// DialNetwork creates new Logger like New writing entries to address on given network (see net.Dial),
+// e.g. DialNetwork("udp", "logs:5140", "info", false). Entries are newline terminated.
+// By default every entry is sent by separate write (TCP segment, UDP datagram), see WithBatch.
+// Batches sent over UDP are limited to 1400 bytes. Close closes the connection.
+// Writes are synchronous by default, i.e. slow connection blocks callers,
+// see WithQueue to choose OverflowPolicy.
+// It returns nil Logger and error if level is not valid or connection fails.
+func DialNetwork(network, address, level string, verbose bool, opts ...Option) (Logger, error) {
+	if _, err := LevelFromString(level); err != nil {
+		return nil, err
+	}
+
+	conn, err := net.Dial(network, address)
+	if err != nil {
+		return nil, err
+	}
+
+	if strings.HasPrefix(network, "udp") {
+		opts = append(opts[:len(opts):len(opts)], func(l *logger) {
+			if l.batchSize > maxDatagram {
+				l.batchSize = maxDatagram
+			}
+		})
+	}
+	return New(conn, level, verbose, opts...)
+}
+
+// WithBatch collects entries written to storage writer (w of New, see DialNetwork) and writes them at once
+// when the batch would exceed size bytes, interval elapses after the first entry of the batch, or on Sync.
+// Entries are never split, longer entries are written alone. It reduces number of writes
+// (e.g. network packets) at cost of delay, pending entries are lost if the process crashes.
+// SetOutput writes pending batch to the old writer and batches the new one. Default is disabled (size <= 0).
+func WithBatch(size int, interval time.Duration) Option {
+	return func(l *logger) {
+		l.batchSize = size
+		l.batchInterval = interval
+	}
+}
+
+// WithQueue queues entries written to storage writer (w of New, see DialNetwork) and writes them
+// by background goroutine, so slow writer (e.g. network connection) blocks callers only if the queue
+// of size entries is full and policy is QueueBlock. Drop policies count dropped entries, see Dropped.
+// Fatal messages are never dropped, Sync writes queued entries and Close stops the goroutine.
+// SetOutput writes queued entries to the old writer and queues the new one with the same policy.
+// Default is disabled (size <= 0), writes are synchronous.
+func WithQueue(size int, policy OverflowPolicy) Option {
+	return func(l *logger) {
+		l.queueSize = size
+		l.queuePolicy = policy
+	}
+}
+
+// queueWriter writes entries to w by background goroutine of asyncQueue, it is safe for concurrent use.
+type queueWriter struct {
+	w   io.Writer
+	q   *asyncQueue
+	mu  sync.Mutex
+	err error // error of queued write, returned by next call
+}
+
+func newQueueWriter(w io.Writer, size int, policy OverflowPolicy) *queueWriter {
+	return &queueWriter{w: w, q: newAsyncQueue(size, policy)}
+}
+
+func (w *queueWriter) Write(p []byte) (int, error) {
+	return w.WriteLevel(InfoLevel, p)
+}
+
+// WriteLevel queues copy of entry p of level lv.
+func (w *queueWriter) WriteLevel(lv Level, p []byte) (int, error) {
+	w.q.send(asyncItem{lv: lv, raw: append([]byte(nil), p...), w: w})
+	return len(p), w.takeErr()
+}
+
+// Flush writes queued entries and flushes w, it is called by Sync.
+func (w *queueWriter) Flush() error {
+	w.q.drain()
+	if err := syncWriter(w.w); err != nil {
+		return err
+	}
+	return w.takeErr()
+}
+
+// Close writes queued entries, stops the goroutine and closes w if it implements io.Closer.
+func (w *queueWriter) Close() error {
+	w.q.close()
+	err := w.takeErr()
+	if c, ok := w.w.(io.Closer); ok {
+		if cerr := c.Close(); err == nil {
+			err = cerr
+		}
+	}
+	return err
+}
+
+// put writes entry p to w, it is called by the goroutine.
+func (w *queueWriter) put(lv Level, p []byte) {
+	_, err := writeLevel(w.w, lv, p)
+
+	w.mu.Lock()
+	if err != nil && w.err == nil {
+		w.err = err
+	}
+	w.mu.Unlock()
+}
+
+func (w *queueWriter) takeErr() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	err := w.err
+	w.err = nil
+	return err
+}
+
+// storageWrap returns function wrapping storage writer by batchWriter and queueWriter configured
+// by WithBatch and WithQueue, nil if neither is enabled.
+func (l *logger) storageWrap() func(io.Writer) io.Writer {
+	if l.batchSize <= 0 && l.queueSize <= 0 {
+		return nil
+	}
+
+	batchSize, interval := l.batchSize, l.batchInterval
+	queueSize, policy := l.queueSize, l.queuePolicy
+	return func(w io.Writer) io.Writer {
+		if batchSize > 0 {
+			w = newBatchWriter(w, batchSize, interval)
+		}
+		if queueSize > 0 {
+			w = newQueueWriter(w, queueSize, policy)
+		}
+		return w
+	}
+}
+
+// batchWriter buffers whole entries and writes them to w in batches, it is safe for concurrent use.
+type batchWriter struct {
+	mu       sync.Mutex
+	w        io.Writer
+	size     int
+	interval time.Duration
+	buf      []byte
+	timer    *time.Timer
+	err      error // error of timed flush, returned by next call
+}
+
+func newBatchWriter(w io.Writer, size int, interval time.Duration) *batchWriter {
+	return &batchWriter{w: w, size: size, interval: interval, buf: make([]byte, 0, size)}
+}
+
+// Write adds entry p to the batch, the batch is written first if p does not fit.
+func (b *batchWriter) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if len(b.buf) > 0 && len(b.buf)+len(p) > b.size {
+		if err := b.flush(); err != nil {
+			return 0, err
+		}
+	}
+	if err := b.takeErr(); err != nil {
+		return 0, err
+	}
+	if len(p) >= b.size {
+		return b.w.Write(p)
+	}
+
+	b.buf = append(b.buf, p...)
+	if b.timer == nil && b.interval > 0 {
+		b.timer = time.AfterFunc(b.interval, b.timedFlush)
+	}
+	return len(p), nil
+}
+
+// Flush writes pending batch, it is called by Sync.
+func (b *batchWriter) Flush() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if err := b.flush(); err != nil {
+		return err
+	}
+	return b.takeErr()
+}
+
+// Close writes pending batch and closes w if it implements io.Closer.
+func (b *batchWriter) Close() error {
+	err := b.Flush()
+	if c, ok := b.w.(io.Closer); ok {
+		if cerr := c.Close(); err == nil {
+			err = cerr
+		}
+	}
+	return err
+}
+
+func (b *batchWriter) timedFlush() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if err := b.flush(); err != nil && b.err == nil {
+		b.err = err
+	}
+}
+
+// flush writes the batch and stops the timer, b.mu must be held.
+func (b *batchWriter) flush() error {
+	if b.timer != nil {
+		b.timer.Stop()
+		b.timer = nil
+	}
+	if len(b.buf) == 0 {
+		return nil
+	}
+
+	_, err := b.w.Write(b.buf)
+	b.buf = b.buf[:0]
+	return err
+}
+
+func (b *batchWriter) takeErr() error {
+	err := b.err
+	b.err = nil
+	return err
+}
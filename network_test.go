@@ -0,0 +1,292 @@
+package clog
+
+import (
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeConn captures every Write as one packet.
+type fakeConn struct {
+	net.Conn
+	mu      sync.Mutex
+	packets []string
+	closed  bool
+}
+
+func (c *fakeConn) Write(p []byte) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.packets = append(c.packets, string(p))
+	return len(p), nil
+}
+
+func (c *fakeConn) Close() error {
+	c.closed = true
+	return nil
+}
+
+func (c *fakeConn) get() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]string(nil), c.packets...)
+}
+
+func TestWithBatch(t *testing.T) {
+	conn := &fakeConn{}
+	l, err := New(conn, "info", false, WithConsole(false), WithFlags(0), WithBatch(64, time.Hour))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	l.Info("one")
+	l.Warn("two")
+	l.Error("three")
+	if p := conn.get(); len(p) != 0 {
+		t.Errorf("expected nothing written before threshold, got: %q", p)
+	}
+	if err := l.Sync(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := []string{"INFO:  one\nWARN:  two\nERROR: three\n"}
+	if p := conn.get(); fmt.Sprint(p) != fmt.Sprint(expected) {
+		t.Errorf("expected: %q, got: %q", expected, p)
+	}
+
+	// size threshold, entries are not split
+	conn.packets = nil
+	for i := 0; i < 5; i++ {
+		l.Info(strings.Repeat("x", 20)) // 28 bytes with prefix and newline
+	}
+	l.Info(strings.Repeat("y", 100))
+	if err := l.Close(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	p := conn.get()
+	if len(p) != 4 || !conn.closed {
+		t.Fatalf("expected 4 writes and closed conn, got %v: %q", conn.closed, p)
+	}
+	for i, n := range []int{2, 2, 1, 1} {
+		if c := strings.Count(p[i], "\n"); c != n || !strings.HasSuffix(p[i], "\n") {
+			t.Errorf("write %d expected %d whole lines, got: %q", i, n, p[i])
+		}
+	}
+}
+
+func TestWithBatchInterval(t *testing.T) {
+	conn := &fakeConn{}
+	l, err := New(conn, "info", false, WithConsole(false), WithFlags(0), WithBatch(4096, 10*time.Millisecond))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	l.Info("a")
+	l.Info("b")
+	deadline := time.Now().Add(time.Second)
+	for len(conn.get()) == 0 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if p := conn.get(); len(p) != 1 || p[0] != "INFO:  a\nINFO:  b\n" {
+		t.Errorf("expected one timed write with both lines, got: %q", p)
+	}
+}
+
+func TestWithBatchSetOutput(t *testing.T) {
+	first, second := &fakeConn{}, &fakeConn{}
+	l, err := New(first, "info", false, WithConsole(false), WithFlags(0), WithBatch(4096, time.Hour))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	l.Info("pending one")
+	l.SetOutput(second)
+	if p := first.get(); len(p) != 1 || p[0] != "INFO:  pending one\n" {
+		t.Errorf("expected pending entry written to old writer, got: %q", p)
+	}
+
+	l.Info("two")
+	l.Info("three")
+	if p := second.get(); len(p) != 0 {
+		t.Errorf("expected new writer batched, got: %q", p)
+	}
+	if err := l.Sync(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if p := second.get(); len(p) != 1 || p[0] != "INFO:  two\nINFO:  three\n" {
+		t.Errorf("expected one batch in new writer, got: %q", p)
+	}
+}
+
+func TestDialNetworkUDP(t *testing.T) {
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Skipf("udp not available: %v", err)
+	}
+	defer pc.Close()
+
+	l, err := DialNetwork("udp", pc.LocalAddr().String(), "info", false,
+		WithConsole(false), WithFlags(0), WithBatch(64<<10, time.Hour))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for i := 0; i < 100; i++ {
+		l.Infof("message %03d %s", i, strings.Repeat("z", 20))
+	}
+	if err := l.Close(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	lines := 0
+	buf := make([]byte, 64<<10)
+	for lines < 100 {
+		if err := pc.SetReadDeadline(time.Now().Add(time.Second)); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		n, _, err := pc.ReadFrom(buf)
+		if err != nil {
+			t.Fatalf("expected 100 lines, got %d: %v", lines, err)
+		}
+		if n > maxDatagram || buf[n-1] != '\n' {
+			t.Errorf("expected whole lines in datagram up to %d bytes, got %d: %q", maxDatagram, n, buf[:n])
+		}
+		lines += strings.Count(string(buf[:n]), "\n")
+	}
+}
+
+func TestDialNetworkInvalidLevel(t *testing.T) {
+	if _, err := DialNetwork("udp", "127.0.0.1:9", "loud", false); err == nil {
+		t.Errorf("expected error for invalid level")
+	}
+}
+
+func TestWithQueue(t *testing.T) {
+	tests := []struct {
+		policy  OverflowPolicy
+		dropped uint64
+		written []string
+	}{
+		{QueueDropNewest, 3, []string{"first", "m0", "m1"}},
+		{QueueDropOldest, 3, []string{"first", "m3", "m4"}},
+		{QueueBlock, 0, []string{"first", "m0", "m1", "m2", "m3", "m4"}},
+	}
+	for _, tt := range tests {
+		conn := &blockingWriter{started: make(chan struct{}), release: make(chan struct{})} // slow connection
+		l, err := New(conn, "info", false, WithConsole(false), WithFlags(0), WithQueue(2, tt.policy))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		l.Info("first")
+		<-conn.started // queue goroutine holds "first", queue is empty
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			for i := 0; i < 5; i++ {
+				l.Infof("m%d", i)
+			}
+		}()
+		if tt.policy == QueueBlock {
+			select {
+			case <-done:
+				t.Errorf("policy %d: expected blocked caller", tt.policy)
+			case <-time.After(20 * time.Millisecond):
+			}
+		} else {
+			<-done
+		}
+		if n := Dropped(l); n != tt.dropped {
+			t.Errorf("policy %d: expected %d dropped entries, got %d", tt.policy, tt.dropped, n)
+		}
+
+		close(conn.release)
+		<-done
+		if err := l.Sync(); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		var got []string
+		for _, s := range conn.lines {
+			got = append(got, strings.TrimPrefix(strings.TrimSuffix(s, "\n"), "INFO:  "))
+		}
+		if strings.Join(got, ",") != strings.Join(tt.written, ",") {
+			t.Errorf("policy %d: expected: %q, got: %q", tt.policy, tt.written, got)
+		}
+		if err := l.Close(); err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+	}
+}
+
+func TestWithQueueFatal(t *testing.T) {
+	conn := &blockingWriter{started: make(chan struct{}), release: make(chan struct{})}
+	var code int
+	l, err := New(conn, "info", false, WithConsole(false), WithFlags(0), WithQueue(1, QueueDropNewest),
+		WithExitFunc(func(c int) { code = c }))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	l.Info("first")
+	<-conn.started
+	l.Info("queued")
+	l.Info("dropped")
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		close(conn.release)
+	}()
+	l.Fatal("bye")
+
+	expected := []string{"INFO:  first\n", "INFO:  queued\n", "FATAL: bye\n"}
+	if fmt.Sprint(conn.lines) != fmt.Sprint(expected) || code != 1 {
+		t.Errorf("expected: %q and exit code 1, got: %q, %d", expected, conn.lines, code)
+	}
+	if n := Dropped(l); n != 1 {
+		t.Errorf("expected 1 dropped entry, got %d", n)
+	}
+}
+
+func TestWithQueueSetOutput(t *testing.T) {
+	first := &blockingWriter{started: make(chan struct{}), release: make(chan struct{})}
+	l, err := New(first, "info", false, WithConsole(false), WithFlags(0), WithQueue(1, QueueDropNewest))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	old := internal(l).storage.get().(*queueWriter)
+
+	l.Info("one")
+	<-first.started
+	l.Info("two")
+	l.Info("dropped")
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		close(first.release)
+	}()
+	second := &fakeConn{}
+	l.SetOutput(second)
+
+	if expected := []string{"INFO:  one\n", "INFO:  two\n"}; fmt.Sprint(first.lines) != fmt.Sprint(expected) {
+		t.Errorf("expected queued entries written to old writer: %q, got: %q", expected, first.lines)
+	}
+	select {
+	case <-old.q.done:
+	default:
+		t.Error("expected goroutine of old queue stopped")
+	}
+	q, ok := internal(l).storage.get().(*queueWriter)
+	if !ok || q.q.policy != QueueDropNewest {
+		t.Fatalf("expected new writer queued with the same policy, got: %T", internal(l).storage.get())
+	}
+	if n := Dropped(l); n != 1 {
+		t.Errorf("expected 1 dropped entry, got %d", n)
+	}
+
+	l.Info("three")
+	if err := l.Sync(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if p := second.get(); len(p) != 1 || p[0] != "INFO:  three\n" {
+		t.Errorf("expected: %q, got: %q", "INFO:  three\n", p)
+	}
+}
@@ -0,0 +1,272 @@
+//
+// Copyright 2017 Radovan Vrždiak. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package clog
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Option configures optional behaviour of Logger created by New.
+// Options are applied in the given order, the last one wins.
+type Option func(*logger)
+
+// WithClock sets the time source used for timestamps and time based features (e.g. WithSampler).
+// Default is time.Now. It is useful mainly in tests to get deterministic output.
+func WithClock(now func() time.Time) Option {
+	return func(l *logger) {
+		if now == nil {
+			now = time.Now
+		}
+		l.now = now
+	}
+}
+
+// WithFlags sets flags of log package controlling the header of entries. Default is log.Ldate | log.Ltime.
+// Supported flags are log.Ldate, log.Ltime, log.Lmicroseconds, log.LUTC, log.Lshortfile and log.Llongfile,
+// e.g. WithFlags(log.Ltime) writes time without date, WithFlags(0) writes no timestamp.
+// log.Lshortfile and log.Llongfile add caller to messages of all levels,
+// not only in DebugLevel (with ShortPath or FullPath, see WithCallerPath).
+// Options modifying flags (e.g. WithMicroseconds) should follow WithFlags.
+func WithFlags(flags int) Option {
+	return func(l *logger) {
+		l.flags = flags
+	}
+}
+
+// WithTimeFormat sets layout (see time.Layout) of timestamps and time fields in all formats,
+// e.g. time.RFC1123 or "2006-01-02 15:04:05.000". It overrides layouts given by flags (see WithFlags),
+// but timestamp is still omitted if flags contain neither date nor time. Time is local unless log.LUTC flag is set.
+func WithTimeFormat(layout string) Option {
+	return func(l *logger) {
+		l.timeFormat = layout
+	}
+}
+
+// WithMicroseconds adds microseconds to timestamps, e.g. "2017/03/09 14:05:07.123456"
+// (see log.Lmicroseconds). It applies to all levels, time fields and JSONFormat.
+func WithMicroseconds() Option {
+	return func(l *logger) {
+		l.flags |= log.Lmicroseconds
+	}
+}
+
+// WithConsole enables or disables all console output of New, NewWithLevel and NewCLI,
+// i.e. stderr mirror of warnings and errors and stdout mirror of verbose info and debug messages.
+// Messages are still written to the storage writer, so WithConsole(false) with a file
+// gives silent but logged run (e.g. for cron jobs). Default is enabled.
+// NewMulti has no implicit console output and it is not affected.
+func WithConsole(enabled bool) Option {
+	return func(l *logger) {
+		l.console = enabled
+	}
+}
+
+// WithWriter adds writers receiving all messages written by the logger in addition to its storage writer,
+// e.g. RingSink next to the log file. Messages are written to storage writer first, then to ws in given order
+// and to console last. Sink of the most verbose level is added for each of ws in NewMulti.
+// SetOutput replaces only the storage writer.
+func WithWriter(ws ...io.Writer) Option {
+	return func(l *logger) {
+		l.extra = append(l.extra, ws...)
+	}
+}
+
+// WithSequence adds sequence number to every written message, starting from 1,
+// e.g. to detect lost messages by gaps in numbers. It is written as "#N" before the message in TextFormat
+// and as "seq" key in JSONFormat and LogfmtFormat. Messages which are not written (below level,
+// sampled out or deduplicated) do not consume numbers. Named children share the sequence, clones start from 1.
+func WithSequence(enabled bool) Option {
+	return func(l *logger) {
+		l.seq = nil
+		if enabled {
+			l.seq = new(uint64)
+		}
+	}
+}
+
+// WithMaxLength limits length of messages to n bytes, longer messages are truncated
+// and suffixed by "…(truncated N bytes)". Fields are not truncated.
+// It protects storage from accidentally logged huge values. Default is unlimited (n <= 0).
+func WithMaxLength(n int) Option {
+	return func(l *logger) {
+		l.maxLen = n
+	}
+}
+
+// WithEscapeControl escapes control characters in messages, e.g. newline is written as `\n`
+// and ESC as `\x1b`, so untrusted input can't forge log lines or send escape sequences to terminal.
+// Single trailing newline is dropped as usual. Fields are not affected. Default is disabled.
+func WithEscapeControl(escape bool) Option {
+	return func(l *logger) {
+		l.escape = escape
+	}
+}
+
+// WithHostname adds host name (see os.Hostname) to every entry, it is resolved once by the option.
+// In TextFormat the host name is written before the message, e.g. "INFO:  2017/03/09 14:05:07 web1 started",
+// in JSONFormat and LogfmtFormat it is "host" key. Default is disabled.
+func WithHostname(enabled bool) Option {
+	return func(l *logger) {
+		l.host = ""
+		if enabled {
+			l.host, _ = os.Hostname()
+		}
+	}
+}
+
+// WithPID adds process ID to entries of all levels, not only to debug messages,
+// written as "[1234] " in TextFormat and "pid" key in JSONFormat and LogfmtFormat.
+// Default is disabled.
+func WithPID(enabled bool) Option {
+	return func(l *logger) {
+		l.showPID = enabled
+	}
+}
+
+// WithStartupLog writes info message recording configuration when the logger is created, e.g.
+// "logger initialized level=info pid=1234 output=/var/log/app.log", to confirm it in captured logs.
+// Output is file name for files and Go type for other writers. It is not written at levels below InfoLevel.
+// Default is disabled.
+func WithStartupLog(enabled bool) Option {
+	return func(l *logger) {
+		l.startupLog = enabled
+	}
+}
+
+// logStartup writes startup message (see WithStartupLog) for storage writers ws.
+func (l *logger) logStartup(ws ...io.Writer) {
+	if !l.startupLog || !l.IsEnabled(InfoLevel) {
+		return
+	}
+
+	names := make([]string, 0, len(ws))
+	for _, w := range ws {
+		switch x := w.(type) {
+		case nil:
+			names = append(names, "none")
+		case *os.File:
+			names = append(names, x.Name())
+		default:
+			names = append(names, fmt.Sprintf("%T", w))
+		}
+	}
+	fields := []Field{{"level", l.level.get().String()}, {"pid", os.Getpid()}, {"output", strings.Join(names, ",")}}
+	l.print(InfoLevel, l.info, entry{prefix: l.prefix, pid: l.pid(), goid: l.goid(), name: l.name, msg: "logger initialized", fields: fields})
+}
+
+// WithSeverity adds syslog severity of entry level as number, i.e. 2 (crit) for fatal messages,
+// 3 (err), 4 (warning), 6 (info) and 7 (debug), for sorting and filtering in tools preferring numbers.
+// It is written as "<N>" before level prefix in TextFormat (the form understood by systemd for stderr)
+// and as "severity" key after level in JSONFormat and LogfmtFormat. Default is disabled.
+func WithSeverity(enabled bool) Option {
+	return func(l *logger) {
+		l.severity = enabled
+	}
+}
+
+// WithGoroutineID adds ID of the logging goroutine next to process ID, i.e. in DebugLevel
+// or at all levels with WithPID. It is written as "[1234:17] " (pid:goid) in TextFormat
+// and "goid" key in JSONFormat and LogfmtFormat. Default is disabled.
+//
+// Go does not expose goroutine IDs on purpose, the ID is parsed from runtime.Stack output
+// which costs about a microsecond per message. It is a debugging aid, don't build logic on it.
+func WithGoroutineID(enabled bool) Option {
+	return func(l *logger) {
+		l.showGoid = enabled
+	}
+}
+
+// CallerPath controls how the source file of caller is shown in debug messages.
+type CallerPath int
+
+// Caller path formats.
+//
+// FullPath is unambiguous and clickable in most editors
+// but it is long and reveals directory structure of the build machine.
+// ShortPath and PackagePath are compact but files with the same name
+// in different packages (or modules) can't be told apart.
+const (
+	FullPath    CallerPath = iota // absolute path as reported by runtime, e.g. /home/user/src/app/db/conn.go
+	ShortPath                     // file name only like log.Lshortfile, e.g. conn.go
+	PackagePath                   // package directory and file name, e.g. db/conn.go
+)
+
+// WithCallerPath sets format of caller's source file path. Default is FullPath.
+func WithCallerPath(p CallerPath) Option {
+	return func(l *logger) {
+		l.callerPath = p
+	}
+}
+
+// trim returns file path (as reported by runtime.Caller) formatted according to p.
+func (p CallerPath) trim(file string) string {
+	switch p {
+	case ShortPath:
+		return filepath.Base(file)
+	case PackagePath:
+		return filepath.Join(filepath.Base(filepath.Dir(file)), filepath.Base(file))
+	}
+	return file
+}
+
+// WithCallerSkip skips n additional stack frames when caller info is resolved.
+// It is intended for libraries wrapping Logger, e.g. WithCallerSkip(1) reports caller of the wrapper
+// instead of the wrapper itself.
+func WithCallerSkip(n int) Option {
+	return func(l *logger) {
+		l.callerSkip = n
+	}
+}
+
+// WithCallerCache caches caller info by program counter of the call site
+// so file, line and function are resolved only once for every call site,
+// e.g. for debug messages logged in tight loops. Cache grows with number of call sites.
+func WithCallerCache(enabled bool) Option {
+	return func(l *logger) {
+		l.callers = nil
+		if enabled {
+			l.callers = &callerCache{}
+		}
+	}
+}
+
+// callerCache maps program counters to formatted caller info, it is shared by children.
+type callerCache struct {
+	m sync.Map // uintptr -> string
+}
+
+// get returns caller info of pc (as returned by runtime.Callers) formatted by l.
+func (c *callerCache) get(l *logger, pc uintptr) string {
+	if s, ok := c.m.Load(pc); ok {
+		return s.(string)
+	}
+
+	frame, _ := runtime.CallersFrames([]uintptr{pc}).Next()
+	file, line, ok := frame.File, frame.Line, frame.File != ""
+	if !ok {
+		file, line = "???", 0
+	}
+	s := l.formatCaller(frame.PC, file, line, ok)
+	c.m.Store(pc, s)
+	return s
+}
+
+// WithCallerFunc adds the function name to caller info of debug messages,
+// e.g. "db.(*Conn).Query (conn.go:42)".
+// It is disabled by default because resolving the function name adds extra cost to every debug message.
+func WithCallerFunc(enabled bool) Option {
+	return func(l *logger) {
+		l.callerFunc = enabled
+	}
+}
@@ -0,0 +1,452 @@
+package clog
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// testNow is a frozen clock for tests, timestamp is "2017/03/09 14:05:07 ".
+func testNow() time.Time {
+	return time.Date(2017, 3, 9, 14, 5, 7, 0, time.Local)
+}
+
+func TestWithClock(t *testing.T) {
+	buf := &bytes.Buffer{}
+	l, err := New(buf, "info", false, WithClock(testNow))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	l.Info("frozen")
+	expected := "INFO:  2017/03/09 14:05:07 frozen\n"
+	if buf.String() != expected {
+		t.Errorf("expected: %q, got: %q", expected, buf.String())
+	}
+}
+
+func TestWithMicroseconds(t *testing.T) {
+	buf := &bytes.Buffer{}
+	now := testNow()
+	clock := func() time.Time {
+		now = now.Add(250 * time.Microsecond)
+		return now
+	}
+	l, err := New(buf, "info", false, WithClock(clock), WithMicroseconds())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	l.Info("first")
+	l.Info("second")
+	l.Warn("third")
+	l.Error("fourth")
+	expected := "INFO:  2017/03/09 14:05:07.000250 first\n" +
+		"INFO:  2017/03/09 14:05:07.000500 second\n" +
+		"WARN:  2017/03/09 14:05:07.000750 third\n" +
+		"ERROR: 2017/03/09 14:05:07.001000 fourth\n"
+	if buf.String() != expected {
+		t.Errorf("expected: %q, got: %q", expected, buf.String())
+	}
+}
+
+func TestWithConsole(t *testing.T) {
+	buf := &bytes.Buffer{}
+	stdout, stderr := captureConsole(t, func() {
+		l, err := New(buf, "info", true, WithClock(testNow), WithConsole(false))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		l.Info("info")
+		l.Error("error")
+	})
+
+	if stdout != "" || stderr != "" {
+		t.Errorf("expected empty console, got stdout: %q, stderr: %q", stdout, stderr)
+	}
+	expected := "INFO:  2017/03/09 14:05:07 info\nERROR: 2017/03/09 14:05:07 error\n"
+	if buf.String() != expected {
+		t.Errorf("expected: %q, got: %q", expected, buf.String())
+	}
+}
+
+func TestWithMaxLength(t *testing.T) {
+	buf := &bytes.Buffer{}
+	l, err := New(buf, "info", false, WithClock(testNow), WithMaxLength(10))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	l.Info(strings.Repeat("x", 1000))
+	l.Errorf("%s", "short")
+	l.Warn("žžžžžž") // 12 bytes, cut must not split rune
+	expected := "INFO:  2017/03/09 14:05:07 xxxxxxxxxx…(truncated 990 bytes)\n" +
+		"ERROR: 2017/03/09 14:05:07 short\n" +
+		"WARN:  2017/03/09 14:05:07 žžžžž…(truncated 2 bytes)\n"
+	if buf.String() != expected {
+		t.Errorf("expected: %q, got: %q", expected, buf.String())
+	}
+}
+
+func TestWithEscapeControl(t *testing.T) {
+	buf := &bytes.Buffer{}
+	l, err := New(buf, "info", false, WithClock(testNow), WithEscapeControl(true))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	user := "bob\nFAKE: injected"
+	l.Infof("login %s", user)
+	l.Warn("tab\there\r\x1b[31mred\u0085ž\n")
+	l.Infow("fields\n", Int("n", 1))
+	expected := "INFO:  2017/03/09 14:05:07 login bob\\nFAKE: injected\n" +
+		"WARN:  2017/03/09 14:05:07 tab\\there\\r\\x1b[31mred\\u0085ž\n" +
+		"INFO:  2017/03/09 14:05:07 fields n=1\n"
+	if buf.String() != expected {
+		t.Errorf("expected: %q, got: %q", expected, buf.String())
+	}
+	if n := strings.Count(buf.String(), "\n"); n != 3 {
+		t.Errorf("expected 3 lines, got %d: %q", n, buf.String())
+	}
+}
+
+func TestWithStartupLog(t *testing.T) {
+	for _, level := range []string{"info", "debug"} {
+		buf := &bytes.Buffer{}
+		l, err := New(buf, level, false, WithClock(testNow), WithConsole(false), WithStartupLog(true))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		l.Info("next")
+
+		expected := fmt.Sprintf("logger initialized level=%s pid=%d output=*bytes.Buffer\n", level, os.Getpid())
+		if n := strings.Count(buf.String(), "logger initialized"); n != 1 || !strings.Contains(buf.String(), expected) {
+			t.Errorf("%s: expected one %q, got: %q", level, expected, buf.String())
+		}
+		if !strings.HasPrefix(buf.String(), "INFO:  2017/03/09 14:05:07 ") {
+			t.Errorf("%s: expected startup line first, got: %q", level, buf.String())
+		}
+	}
+
+	// opt-in
+	buf := &bytes.Buffer{}
+	if _, err := New(buf, "info", false, WithConsole(false)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if buf.Len() != 0 {
+		t.Errorf("expected no startup line by default, got: %q", buf.String())
+	}
+}
+
+func TestWithStartupLogWrapped(t *testing.T) {
+	buf := &bytes.Buffer{}
+	l, err := New(buf, "info", false, WithConsole(false), WithStartupLog(true),
+		WithBatch(4096, time.Hour), WithQueue(16, QueueBlock))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := l.Sync(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if !strings.Contains(buf.String(), " output=*bytes.Buffer\n") {
+		t.Errorf("expected writer given to New, got: %q", buf.String())
+	}
+}
+
+func TestWithGoroutineID(t *testing.T) {
+	buf := &bytes.Buffer{}
+	l, err := New(buf, "debug", false, WithClock(testNow), WithConsole(false), WithGoroutineID(true))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 2; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			l.Debugf("worker %d", i)
+		}(i)
+	}
+	wg.Wait()
+
+	re := regexp.MustCompile(fmt.Sprintf(`^DEBUG: 2017/03/09 14:05:07 \[%d:(\d+)\] \S+ worker \d$`, os.Getpid()))
+	goids := map[string]bool{}
+	for _, line := range strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n") {
+		m := re.FindStringSubmatch(line)
+		if m == nil {
+			t.Fatalf("expected pid:goid in line, got: %q", line)
+		}
+		goids[m[1]] = true
+	}
+	if len(goids) != 2 {
+		t.Errorf("expected 2 different goroutine IDs, got: %v", goids)
+	}
+
+	// debug-gated by default
+	buf.Reset()
+	l, err = New(buf, "info", false, WithClock(testNow), WithConsole(false), WithGoroutineID(true), WithFormat(JSONFormat))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	l.Info("no id")
+	if strings.Contains(buf.String(), "goid") {
+		t.Errorf("expected no goroutine ID at info level, got: %q", buf.String())
+	}
+	internal(l).showPID = true
+	l.Info("with id")
+	if !regexp.MustCompile(`"pid":\d+,"goid":\d+,`).MatchString(buf.String()) {
+		t.Errorf("expected goid key with WithPID, got: %q", buf.String())
+	}
+}
+
+func TestWithSeverity(t *testing.T) {
+	log := func(l Logger) {
+		l.Debug("d")
+		l.Info("i")
+		l.Warn("w")
+		l.Error("e")
+		l.Fatal("f")
+	}
+
+	buf := &bytes.Buffer{}
+	l, err := New(buf, "debug", false, WithConsole(false), WithFlags(0), WithSeverity(true), WithExitFunc(func(int) {}))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	log(l)
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	prefixes := []string{"<7>DEBUG: ", "<6>INFO:  ", "<4>WARN:  ", "<3>ERROR: ", "<2>FATAL: "}
+	if len(lines) != len(prefixes) {
+		t.Fatalf("expected %d lines, got: %q", len(prefixes), buf.String())
+	}
+	for i, p := range prefixes {
+		if !strings.HasPrefix(lines[i], p) {
+			t.Errorf("expected prefix %q, got: %q", p, lines[i])
+		}
+	}
+
+	buf.Reset()
+	l, err = New(buf, "debug", false, WithConsole(false), WithFlags(0), WithSeverity(true),
+		WithFormat(JSONFormat), WithExitFunc(func(int) {}))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	log(l)
+	var severities []int
+	for _, line := range strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n") {
+		var v struct{ Severity int }
+		if err := json.Unmarshal([]byte(line), &v); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		severities = append(severities, v.Severity)
+	}
+	if fmt.Sprint(severities) != "[7 6 4 3 2]" {
+		t.Errorf("expected: [7 6 4 3 2], got: %v", severities)
+	}
+}
+
+func TestWithHostnamePID(t *testing.T) {
+	host, err := os.Hostname()
+	if err != nil || host == "" {
+		t.Skipf("hostname not available: %v", err)
+	}
+
+	buf := &bytes.Buffer{}
+	l, err := New(buf, "info", false, WithClock(testNow), WithHostname(true), WithPID(true))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	l.Info("started")
+	expected := fmt.Sprintf("INFO:  2017/03/09 14:05:07 %s [%d] started\n", host, os.Getpid())
+	if buf.String() != expected {
+		t.Errorf("expected: %q, got: %q", expected, buf.String())
+	}
+
+	buf.Reset()
+	l, err = New(buf, "info", false, WithClock(testNow))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	l.Info("started")
+	if expected := "INFO:  2017/03/09 14:05:07 started\n"; buf.String() != expected {
+		t.Errorf("default: expected: %q, got: %q", expected, buf.String())
+	}
+}
+
+func TestWithHostnamePIDJSON(t *testing.T) {
+	host, err := os.Hostname()
+	if err != nil || host == "" {
+		t.Skipf("hostname not available: %v", err)
+	}
+
+	buf := &bytes.Buffer{}
+	l, err := New(buf, "info", false, WithFormat(JSONFormat), WithHostname(true), WithPID(true))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	internal(l).flags = 0 // no time
+
+	l.Info("started")
+	expected := fmt.Sprintf(`{"level":"info","host":%q,"pid":%d,"msg":"started"}`+"\n", host, os.Getpid())
+	if buf.String() != expected {
+		t.Errorf("expected: %q, got: %q", expected, buf.String())
+	}
+}
+
+func TestWithFlags(t *testing.T) {
+	buf := &bytes.Buffer{}
+	l, err := New(buf, "info", false, WithClock(testNow), WithFlags(log.Ltime))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	l.Info("time only")
+	if expected := "INFO:  14:05:07 time only\n"; buf.String() != expected {
+		t.Errorf("expected: %q, got: %q", expected, buf.String())
+	}
+
+	buf.Reset()
+	l, err = New(buf, "info", false, WithClock(testNow), WithFlags(log.Ltime|log.Lshortfile))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	l.Info("with file")
+	if got := buf.String(); !strings.HasPrefix(got, "INFO:  14:05:07 options_test.go:") || !strings.HasSuffix(got, " with file\n") {
+		t.Errorf("expected short file at info level, got: %q", got)
+	}
+}
+
+// orderWriter records name of the writer for every write into shared order.
+type orderWriter struct {
+	name  string
+	order *[]string
+}
+
+func (w orderWriter) Write(p []byte) (int, error) {
+	*w.order = append(*w.order, w.name)
+	return len(p), nil
+}
+
+func TestWithWriter(t *testing.T) {
+	tmp, err := ioutil.TempDir("", "clog-test")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer os.RemoveAll(tmp)
+
+	f, err := OpenFile(filepath.Join(tmp, "app.log"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer f.Close()
+
+	extra := &bytes.Buffer{}
+	l, err := New(f, "info", false, WithClock(testNow), WithConsole(false), WithWriter(extra))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	l.Info("connected")
+	l.Debug("hidden")
+
+	expected := "INFO:  2017/03/09 14:05:07 connected\n"
+	b, err := ioutil.ReadFile(f.Name())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if string(b) != expected {
+		t.Errorf("file expected: %q, got: %q", expected, b)
+	}
+	if extra.String() != expected {
+		t.Errorf("extra writer expected: %q, got: %q", expected, extra.String())
+	}
+
+	var order []string
+	ws := []io.Writer{orderWriter{"extra1", &order}, orderWriter{"extra2", &order}}
+	l, err = New(orderWriter{"primary", &order}, "info", false, WithConsole(false), WithWriter(ws...))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	l.Warn("w")
+	l, err = NewMulti([]Sink{{Writer: orderWriter{"sink", &order}, Level: WarnLevel}}, WithWriter(ws[0]))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	l.Warn("w")
+	if got := strings.Join(order, ","); got != "primary,extra1,extra2,sink,extra1" {
+		t.Errorf("expected order: %q, got: %q", "primary,extra1,extra2,sink,extra1", got)
+	}
+}
+
+func TestWithTimeFormat(t *testing.T) {
+	layout := "2006-01-02 15:04:05.000 MST"
+	stamp := testNow().Add(123 * time.Millisecond).UTC()
+	now := func() time.Time { return stamp }
+	expected := "2017-03-09 " + stamp.Format("15:04:05") + ".123 UTC"
+
+	buf := &bytes.Buffer{}
+	l, err := New(buf, "info", false, WithClock(now), WithConsole(false), WithTimeFormat(layout), WithFlags(log.LstdFlags|log.LUTC))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	l.Infow("text", Time("at", stamp))
+	if s := "INFO:  " + expected + " text at=\"" + expected + "\"\n"; buf.String() != s {
+		t.Errorf("text expected: %q, got: %q", s, buf.String())
+	}
+
+	buf.Reset()
+	l, err = New(buf, "info", false, WithClock(now), WithConsole(false), WithTimeFormat(layout),
+		WithFlags(log.LstdFlags|log.LUTC), WithFormat(JSONFormat))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	l.Info("json")
+	var v struct{ Time string }
+	if err := json.Unmarshal(buf.Bytes(), &v); err != nil {
+		t.Fatalf("invalid JSON %q: %v", buf.String(), err)
+	}
+	if v.Time != expected {
+		t.Errorf("JSON time expected: %q, got: %q", expected, v.Time)
+	}
+}
+
+func TestWithSequence(t *testing.T) {
+	buf := &bytes.Buffer{}
+	l, err := New(buf, "info", false, WithConsole(false), WithFlags(0), WithSequence(true))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	l.Info("a")
+	l.Debug("hidden")
+	l.Named("sub").Warn("b")
+	l.Debugf("hidden %d", 2)
+	l.Error("c")
+	expected := "INFO:  #1 a\nWARN:  #2 sub: b\nERROR: #3 c\n"
+	if buf.String() != expected {
+		t.Errorf("expected: %q, got: %q", expected, buf.String())
+	}
+
+	buf.Reset()
+	l, err = New(buf, "info", false, WithConsole(false), WithFlags(0), WithSequence(true), WithFormat(JSONFormat))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	l.Info("a")
+	l.Info("b")
+	expected = `{"level":"info","seq":1,"msg":"a"}` + "\n" + `{"level":"info","seq":2,"msg":"b"}` + "\n"
+	if buf.String() != expected {
+		t.Errorf("expected: %q, got: %q", expected, buf.String())
+	}
+}
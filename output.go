@@ -0,0 +1,472 @@
+//
+// Copyright 2017 Radovan Vrždiak. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package clog
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"unicode/utf8"
+)
+
+// WithoutNewline disables the trailing newline appended to every entry
+// (log.Logger style). The caller is responsible for termination of entries.
+func WithoutNewline() Option {
+	return func(l *logger) {
+		l.noNewline = true
+	}
+}
+
+// WithNewlineReplacement replaces newlines embedded in messages by sep (e.g. "\t")
+// so every entry stays on one physical line, which suits grep or JSON based pipelines.
+// Trailing newline of the message is removed first.
+func WithNewlineReplacement(sep string) Option {
+	return func(l *logger) {
+		l.newlineSep = &sep
+	}
+}
+
+// WithLevelPrefixes overrides prefixes written before entries of given levels,
+// e.g. {ErrorLevel: "E "}. DisabledLevel stands for fatal messages.
+// Levels without override keep the default padded names "FATAL: ", "ERROR: ", ... "DEBUG: ".
+// Prefixes are written only in TextFormat.
+func WithLevelPrefixes(prefixes map[Level]string) Option {
+	return func(l *logger) {
+		if l.prefixes == nil {
+			l.prefixes = make(map[Level]string, len(prefixes))
+		}
+		for lv, p := range prefixes {
+			l.prefixes[lv] = p
+		}
+	}
+}
+
+// WithoutStorageLevelPrefix omits level prefix of entries written to storage writer
+// (w of New or sinks of NewMulti), e.g. for systemd-journald which adds priority itself.
+// Console mirror keeps the prefix. See WithLevelPrefixes.
+func WithoutStorageLevelPrefix() Option {
+	return func(l *logger) {
+		l.noStoragePrefix = true
+	}
+}
+
+// WithColor colors level prefix of entries mirrored to console (stdout, stderr) by ANSI escape codes:
+// fatal and error red, warning yellow, info green and debug cyan.
+// Only the level name is colored, padding, message and fields stay plain so tools scanning messages
+// are not confused. Storage writer is never colored. It applies to TextFormat only.
+func WithColor() Option {
+	return func(l *logger) {
+		l.color = true
+	}
+}
+
+// levelColors are ANSI color codes of level prefixes, see WithColor.
+var levelColors = map[Level]string{
+	DisabledLevel: "31", // red
+	ErrorLevel:    "31", // red
+	WarnLevel:     "33", // yellow
+	InfoLevel:     "32", // green
+	DebugLevel:    "36", // cyan
+}
+
+// colorPrefix returns prefix with level name (without trailing spaces) wrapped by escape codes of lv color.
+func colorPrefix(lv Level, prefix string) string {
+	name := strings.TrimRight(prefix, " ")
+	if name == "" {
+		return prefix
+	}
+	return "\x1b[" + levelColors[lv] + "m" + name + "\x1b[0m" + prefix[len(name):]
+}
+
+// WithShortLevelPrefixes writes level as single uppercase letter and space:
+// "F ", "E ", "W ", "I ", "D ". See WithLevelPrefixes.
+func WithShortLevelPrefixes() Option {
+	return WithLevelPrefixes(shortPrefixes)
+}
+
+var defaultPrefixes = map[Level]string{
+	DisabledLevel: "FATAL: ",
+	ErrorLevel:    "ERROR: ",
+	WarnLevel:     "WARN:  ",
+	InfoLevel:     "INFO:  ",
+	DebugLevel:    "DEBUG: ",
+}
+
+var shortPrefixes = map[Level]string{
+	DisabledLevel: "F ",
+	ErrorLevel:    "E ",
+	WarnLevel:     "W ",
+	InfoLevel:     "I ",
+	DebugLevel:    "D ",
+}
+
+// WriteRaw writes pre-formatted p as it is to writers of given level,
+// including the console mirror. Prefix, timestamp and other message decorations
+// are not added and the caller is responsible for the trailing newline.
+// It is intended for hot paths where callers format (and pool) their own buffers.
+// Nothing is written if level is not enabled.
+func (l *logger) WriteRaw(level Level, p []byte) {
+	if l == nil || !l.levelEnabled(level) {
+		return // Don't log at lower levels.
+	}
+	if out := l.outputFor(level); out != nil && !l.isClosed() {
+		out.write(p)
+		if out.mirror != nil {
+			out.mirror.write(p)
+		}
+	}
+}
+
+// output writes entries of one level prefixed by level name (in TextFormat).
+// It mimics log.Logger (without header flags) but gives control over line termination.
+type output struct {
+	mu         sync.Mutex
+	w          io.Writer
+	level      Level // DisabledLevel stands for fatal
+	prefix     string
+	newline    bool    // append newline if missing
+	newlineSep *string // replacement of embedded newlines, nil - keep them
+	width      int     // visible width of prefix, see WithMessageColumn
+	format     Format
+	mirror     *output // console mirror in another format, see WithConsoleFormat
+	buf        []byte
+}
+
+// newOutput returns output of storage writers (including console if it is not mirrored separately).
+func (l *logger) newOutput(w io.Writer, level Level) *output {
+	o := l.newFormatOutput(w, level, l.format)
+	if l.noStoragePrefix {
+		o.prefix = l.severityPrefix(level, l.format)
+		o.width = len(o.prefix)
+	}
+	return o
+}
+
+// severityPrefix returns "<N>" written before level prefix in TextFormat, see WithSeverity.
+func (l *logger) severityPrefix(level Level, f Format) string {
+	if !l.severity || f != TextFormat {
+		return ""
+	}
+	return "<" + strconv.Itoa(syslogSeverity(level)) + ">"
+}
+
+func (l *logger) newFormatOutput(w io.Writer, level Level, f Format) *output {
+	prefix, ok := l.prefixes[level]
+	if !ok {
+		prefix = defaultPrefixes[level]
+	}
+	if f != TextFormat {
+		prefix = "" // level is a key
+	}
+	prefix = l.severityPrefix(level, f) + prefix
+	return &output{w: w, level: level, prefix: prefix, newline: !l.noNewline, newlineSep: l.newlineSep,
+		width: utf8.RuneCountInString(prefix), format: f}
+}
+
+// mirrored reports whether console is written by separate outputs (see setupMirrors).
+func (l *logger) mirrored() bool {
+	formats := l.consoleFormat != nil && *l.consoleFormat != l.format
+	return l.console && (formats || l.noStoragePrefix || l.color)
+}
+
+// setupMirrors adds console mirrors to outputs created by setup,
+// fatal, error and warn are mirrored to stderr, info and debug to stdout in verbose mode.
+func (l *logger) setupMirrors() {
+	f := l.format
+	if l.consoleFormat != nil {
+		f = *l.consoleFormat
+	}
+
+	for _, out := range []*output{l.fatal, l.error, l.warn, l.info, l.debug} {
+		switch {
+		case out == nil:
+			continue
+		case out.level <= WarnLevel:
+			out.mirror = l.newFormatOutput(os.Stderr, out.level, f)
+		case l.verbose:
+			out.mirror = l.newFormatOutput(os.Stdout, out.level, f)
+		default:
+			continue
+		}
+		if l.color {
+			out.mirror.prefix = colorPrefix(out.level, out.mirror.prefix)
+		}
+	}
+}
+
+// Print writes prefix and encoded entry s as one entry.
+func (o *output) Print(s []byte) {
+	if o.newlineSep != nil {
+		s = bytes.ReplaceAll(bytes.TrimSuffix(s, []byte("\n")), []byte("\n"), []byte(*o.newlineSep))
+	}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	o.buf = append(o.buf[:0], o.prefix...)
+	o.buf = append(o.buf, s...)
+	if o.newline && (len(s) == 0 || s[len(s)-1] != '\n') {
+		o.buf = append(o.buf, '\n')
+	}
+	writeLevel(o.w, o.level, o.buf)
+}
+
+// write writes p without any processing.
+func (o *output) write(p []byte) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	writeLevel(o.w, o.level, p)
+}
+
+// OnWriteError registers fn called when write to storage writer fails,
+// e.g. to fall back to stderr or to increment a metric.
+// Storage writer is w of New (NewWithLevel, NewCLI) or any sink of NewMulti.
+// Failed write does not prevent writing of the entry to console mirror,
+// errors of console mirror (stdout, stderr) are ignored.
+// fn is called while the entry is being written so it must not log to the same logger.
+func OnWriteError(fn func(error)) Option {
+	return func(l *logger) {
+		l.onError = fn
+	}
+}
+
+// reportErrors returns w reporting write errors to l.onError, w is returned if there is no callback.
+func (l *logger) reportErrors(w io.Writer) io.Writer {
+	if l.onError == nil {
+		return w
+	}
+	return errorWriter{w: w, fn: l.onError}
+}
+
+// errorWriter reports write errors of w to fn and hides them from the caller
+// so multiWriter continues with other writers.
+type errorWriter struct {
+	w  io.Writer
+	fn func(error)
+}
+
+func (e errorWriter) Write(p []byte) (int, error) {
+	n, err := e.w.Write(p)
+	return e.report(p, n, err)
+}
+
+func (e errorWriter) WriteLevel(lv Level, p []byte) (int, error) {
+	n, err := writeLevel(e.w, lv, p)
+	return e.report(p, n, err)
+}
+
+func (e errorWriter) report(p []byte, n int, err error) (int, error) {
+	if err == nil && n < len(p) {
+		err = io.ErrShortWrite
+	}
+	if err != nil {
+		e.fn(err)
+	}
+	return len(p), nil
+}
+
+// LevelWriter is implemented by writers which want to know level of written entries,
+// e.g. syslog-like writers or level aware network posters.
+// Logger calls WriteLevel instead of Write for such writers (including New's w and sinks of NewMulti).
+// DisabledLevel stands for fatal messages.
+type LevelWriter interface {
+	WriteLevel(level Level, p []byte) (n int, err error)
+}
+
+// writeLevel writes p to w using WriteLevel if w implements LevelWriter.
+func writeLevel(w io.Writer, lv Level, p []byte) (int, error) {
+	if lw, ok := w.(LevelWriter); ok {
+		return lw.WriteLevel(lv, p)
+	}
+	return w.Write(p)
+}
+
+// multiWriter is io.MultiWriter passing level to LevelWriters.
+// Unlike io.MultiWriter it writes to all writers even if some of them fail
+// so failing console does not prevent storage write and vice versa.
+// The first error is returned.
+type multiWriter []io.Writer
+
+func newMultiWriter(ws ...io.Writer) io.Writer {
+	if len(ws) == 1 {
+		return ws[0]
+	}
+	return multiWriter(ws)
+}
+
+func (m multiWriter) Write(p []byte) (int, error) {
+	var first error
+	for _, w := range m {
+		n, err := w.Write(p)
+		first = firstWriteError(first, p, n, err)
+	}
+	return len(p), first
+}
+
+func (m multiWriter) WriteLevel(lv Level, p []byte) (int, error) {
+	var first error
+	for _, w := range m {
+		n, err := writeLevel(w, lv, p)
+		first = firstWriteError(first, p, n, err)
+	}
+	return len(p), first
+}
+
+// firstWriteError returns first or error of write of n bytes of p.
+func firstWriteError(first error, p []byte, n int, err error) error {
+	if first != nil {
+		return first
+	}
+	if err == nil && n < len(p) {
+		return io.ErrShortWrite
+	}
+	return err
+}
+
+// SetOutput replaces the storage writer, i.e. writer given to New or the first sink of NewMulti.
+// Console output is not affected. Nil w discards the storage output.
+// Queued and batched entries (see WithQueue, WithBatch) are written to the old writer,
+// w is queued and batched as well.
+// It is safe to call concurrently with logging, child loggers (see Named) are affected too.
+func (l *logger) SetOutput(w io.Writer) {
+	if l == nil || l.storage == nil {
+		return
+	}
+
+	l.storage.set(w)
+	l.w = w
+}
+
+// Truncate truncates the storage writer (see SetOutput) to zero size if it is *os.File,
+// e.g. on request of admin endpoint when logs are not rotated.
+// It returns error if the storage writer is not a file. Concurrent writes wait until truncation is done.
+func (l *logger) Truncate() error {
+	if l == nil || l.storage == nil {
+		return fmt.Errorf("log has no storage writer")
+	}
+	return l.storage.truncate()
+}
+
+// switchWriter is io.Writer whose destination can be replaced while in use.
+type switchWriter struct {
+	mu   sync.RWMutex
+	w    io.Writer
+	raw  io.Writer                 // w before wrap
+	orig io.Writer                 // restored by reset
+	wrap func(io.Writer) io.Writer // batching and queueing of writes, see WithBatch and WithQueue
+}
+
+// newSwitchWriter returns switchWriter writing to w wrapped by wrap, wrap may be nil.
+func newSwitchWriter(w io.Writer, wrap func(io.Writer) io.Writer) *switchWriter {
+	s := &switchWriter{orig: w, wrap: wrap}
+	s.set(w)
+	return s
+}
+
+func (s *switchWriter) Write(p []byte) (int, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.w.Write(p)
+}
+
+func (s *switchWriter) WriteLevel(lv Level, p []byte) (int, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return writeLevel(s.w, lv, p)
+}
+
+// set replaces the writer, pending entries of the old wrapper are written to the old writer first.
+func (s *switchWriter) set(w io.Writer) {
+	raw := w
+	if w == nil {
+		raw, w = ioutil.Discard, ioutil.Discard
+	} else if s.wrap != nil {
+		w = s.wrap(w)
+	}
+
+	s.mu.Lock()
+	old := s.w
+	s.w, s.raw = w, raw
+	s.mu.Unlock()
+
+	release(old, w)
+}
+
+// release writes pending entries of wrapper old replaced by w and stops its goroutine,
+// wrapped writer is not closed. Dropped entries are counted by w.
+func release(old, w io.Writer) {
+	if q, ok := old.(*queueWriter); ok {
+		q.q.close()
+		if n, ok := w.(*queueWriter); ok {
+			atomic.AddUint64(&n.q.dropped, atomic.LoadUint64(&q.q.dropped))
+		}
+		old = q.w
+	}
+	if b, ok := old.(*batchWriter); ok {
+		b.Flush()
+	}
+}
+
+// reset restores writer given to newSwitchWriter.
+func (s *switchWriter) reset() {
+	s.set(s.orig)
+}
+
+// truncate truncates file written by s to zero size and seeks to its start.
+// Queued and batched entries (see WithQueue, WithBatch) are written first. Writes are blocked meanwhile.
+func (s *switchWriter) truncate() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	w := s.w
+	if q, ok := w.(*queueWriter); ok {
+		q.q.drain()
+		w = q.w
+	}
+	if b, ok := w.(*batchWriter); ok {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+
+		if err := b.flush(); err != nil {
+			return err
+		}
+		w = b.w
+	}
+
+	f, ok := w.(*os.File)
+	if !ok {
+		return fmt.Errorf("log storage writer %T is not a file", w)
+	}
+	if err := f.Truncate(0); err != nil {
+		return err
+	}
+	_, err := f.Seek(0, io.SeekStart)
+	return err
+}
+
+func (s *switchWriter) get() io.Writer {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.w
+}
+
+// unwrapped returns the writer given to set.
+func (s *switchWriter) unwrapped() io.Writer {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.raw
+}
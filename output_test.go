@@ -0,0 +1,342 @@
+package clog
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestNewlineHandling(t *testing.T) {
+	tests := []struct {
+		name     string
+		opts     []Option
+		msg      string
+		expected string
+	}{
+		{"default", nil, "a\nb", "INFO:  2017/03/09 14:05:07 a\nb\n"},
+		{"default trailing", nil, "a\nb\n", "INFO:  2017/03/09 14:05:07 a\nb\n"},
+		{"no newline", []Option{WithoutNewline()}, "a\nb", "INFO:  2017/03/09 14:05:07 a\nb"},
+		{"replacement", []Option{WithNewlineReplacement("\t")}, "a\nb\nc\n", "INFO:  2017/03/09 14:05:07 a\tb\tc\n"},
+		{"replacement no newline", []Option{WithNewlineReplacement(" | "), WithoutNewline()}, "a\nb", "INFO:  2017/03/09 14:05:07 a | b"},
+	}
+
+	for _, tt := range tests {
+		buf := &bytes.Buffer{}
+		opts := append([]Option{WithClock(testNow)}, tt.opts...)
+		l, err := New(buf, "info", false, opts...)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", tt.name, err)
+		}
+
+		l.Info(tt.msg)
+		if buf.String() != tt.expected {
+			t.Errorf("%s: expected: %q, got: %q", tt.name, tt.expected, buf.String())
+		}
+	}
+}
+
+func TestWriteRaw(t *testing.T) {
+	buf := &bytes.Buffer{}
+	l, err := New(buf, "info", false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	l.WriteRaw(InfoLevel, []byte("raw line\n"))
+	l.WriteRaw(DebugLevel, []byte("not enabled\n"))
+	l.WriteRaw(Level(999), []byte("invalid level\n"))
+	if buf.String() != "raw line\n" {
+		t.Errorf("expected: %q, got: %q", "raw line\n", buf.String())
+	}
+}
+
+func TestWriteRawMirror(t *testing.T) {
+	for _, opt := range []Option{WithColor(), WithConsoleFormat(JSONFormat), WithoutStorageLevelPrefix()} {
+		buf := &bytes.Buffer{}
+		_, stderr := captureConsole(t, func() {
+			l, err := New(buf, "info", false, opt)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			l.WriteRaw(ErrorLevel, []byte("raw err\n"))
+		})
+		if buf.String() != "raw err\n" || stderr != "raw err\n" {
+			t.Errorf("expected raw line in storage and console, got: %q, %q", buf.String(), stderr)
+		}
+	}
+}
+
+func BenchmarkWriteRaw(b *testing.B) {
+	l, _ := New(ioutil.Discard, "info", false)
+	p := []byte("INFO:  request handled status=200\n")
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		l.WriteRaw(InfoLevel, p)
+	}
+}
+
+func BenchmarkWriteRawInfo(b *testing.B) {
+	l, _ := New(ioutil.Discard, "info", false)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		l.Info("request handled status=200")
+	}
+}
+
+func TestSetOutput(t *testing.T) {
+	first, second := &bytes.Buffer{}, &bytes.Buffer{}
+	l, err := New(first, "info", false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	child := l.Named("child")
+
+	l.Info("one")
+	l.SetOutput(second)
+	l.Info("two")
+	child.Info("three")
+	l.SetOutput(nil) // discard
+	l.Info("four")
+
+	if !strings.Contains(first.String(), "one") || strings.Contains(first.String(), "two") {
+		t.Errorf("unexpected first output: %q", first.String())
+	}
+	if !strings.Contains(second.String(), "two") || !strings.Contains(second.String(), "child: three") {
+		t.Errorf("unexpected second output: %q", second.String())
+	}
+	if strings.Contains(second.String(), "four") {
+		t.Errorf("nil output should discard messages, got: %q", second.String())
+	}
+}
+
+func TestShortLevelPrefixes(t *testing.T) {
+	buf := &bytes.Buffer{}
+	l, err := New(buf, "info", false, WithClock(testNow), WithShortLevelPrefixes())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	l.Warn("w")
+	l.Error("e")
+	got := strings.Split(buf.String(), "\n")
+	if !strings.HasPrefix(got[0], "W ") || !strings.HasPrefix(got[1], "E ") {
+		t.Errorf("expected W and E prefixes, got: %q", buf.String())
+	}
+	if got[0] != "W 2017/03/09 14:05:07 w" {
+		t.Errorf("expected: %q, got: %q", "W 2017/03/09 14:05:07 w", got[0])
+	}
+}
+
+func TestLevelPrefixes(t *testing.T) {
+	buf := &bytes.Buffer{}
+	l, err := New(buf, "info", false, WithClock(testNow), WithLevelPrefixes(map[Level]string{InfoLevel: "[info] "}))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	l.Info("i")
+	l.Warn("w")
+	expected := "[info] 2017/03/09 14:05:07 i\nWARN:  2017/03/09 14:05:07 w\n"
+	if buf.String() != expected {
+		t.Errorf("expected: %q, got: %q", expected, buf.String())
+	}
+}
+
+type levelRecorder struct {
+	levels []Level
+	lines  []string
+}
+
+func (r *levelRecorder) Write(p []byte) (int, error) {
+	return r.WriteLevel(InvalidLevel, p)
+}
+
+func (r *levelRecorder) WriteLevel(lv Level, p []byte) (int, error) {
+	r.levels = append(r.levels, lv)
+	r.lines = append(r.lines, string(p))
+	return len(p), nil
+}
+
+func TestLevelWriter(t *testing.T) {
+	rec := &levelRecorder{}
+	l, err := New(rec, "info", true, WithClock(testNow), WithConsole(false))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	internal(l).exit = func(int) {}
+
+	l.Info("i")
+	l.Error("e")
+	l.WriteRaw(WarnLevel, []byte("raw\n"))
+	l.Fatal("f")
+
+	expectedLevels := []Level{InfoLevel, ErrorLevel, WarnLevel, DisabledLevel}
+	expectedLines := []string{
+		"INFO:  2017/03/09 14:05:07 i\n",
+		"ERROR: 2017/03/09 14:05:07 e\n",
+		"raw\n",
+		"FATAL: 2017/03/09 14:05:07 f\n",
+	}
+	if fmt.Sprint(rec.levels) != fmt.Sprint(expectedLevels) {
+		t.Errorf("expected levels: %v, got: %v", expectedLevels, rec.levels)
+	}
+	if strings.Join(rec.lines, "") != strings.Join(expectedLines, "") {
+		t.Errorf("expected: %q, got: %q", expectedLines, rec.lines)
+	}
+}
+
+func TestLevelWriterSinks(t *testing.T) {
+	rec := &levelRecorder{}
+	buf := &bytes.Buffer{}
+	l, err := NewMulti([]Sink{{Writer: buf, Level: InfoLevel}, {Writer: rec, Level: WarnLevel}}, WithClock(testNow))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	l.Info("i")
+	l.Warn("w")
+	if fmt.Sprint(rec.levels) != fmt.Sprint([]Level{WarnLevel}) {
+		t.Errorf("expected levels: [%v], got: %v", WarnLevel, rec.levels)
+	}
+	if expected := "INFO:  2017/03/09 14:05:07 i\nWARN:  2017/03/09 14:05:07 w\n"; buf.String() != expected {
+		t.Errorf("plain writer: expected: %q, got: %q", expected, buf.String())
+	}
+}
+
+type failingWriter struct{}
+
+func (failingWriter) Write(p []byte) (int, error) {
+	return 0, errors.New("disk full")
+}
+
+func TestOnWriteError(t *testing.T) {
+	var errs []error
+	stdout, stderr := captureConsole(t, func() {
+		l, err := New(failingWriter{}, "info", true, WithClock(testNow), OnWriteError(func(err error) {
+			errs = append(errs, err)
+		}))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		l.Info("i")
+		l.Error("e")
+	})
+
+	if len(errs) != 2 || errs[0].Error() != "disk full" {
+		t.Errorf("expected 2 disk full errors, got: %v", errs)
+	}
+	// console mirror still gets entries
+	if stdout != "INFO:  2017/03/09 14:05:07 i\n" || stderr != "ERROR: 2017/03/09 14:05:07 e\n" {
+		t.Errorf("unexpected console output, stdout: %q, stderr: %q", stdout, stderr)
+	}
+}
+
+func TestOnWriteErrorSinks(t *testing.T) {
+	var errs []error
+	buf := &bytes.Buffer{}
+	sinks := []Sink{{Writer: buf, Level: InfoLevel}, {Writer: failingWriter{}, Level: InfoLevel}}
+	l, err := NewMulti(sinks, WithClock(testNow), OnWriteError(func(err error) { errs = append(errs, err) }))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	l.Info("i")
+	if len(errs) != 1 {
+		t.Errorf("expected 1 error, got: %v", errs)
+	}
+	if expected := "INFO:  2017/03/09 14:05:07 i\n"; buf.String() != expected {
+		t.Errorf("expected: %q, got: %q", expected, buf.String())
+	}
+}
+
+func TestClosedConsole(t *testing.T) {
+	closed, err := ioutil.TempFile("", "clog")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	os.Remove(closed.Name())
+	closed.Close()
+
+	origOut, origErr := os.Stdout, os.Stderr
+	os.Stdout, os.Stderr = closed, closed
+	buf := &bytes.Buffer{}
+	var errs []error
+	l, err := New(buf, "info", true, WithClock(testNow), OnWriteError(func(err error) { errs = append(errs, err) }))
+	os.Stdout, os.Stderr = origOut, origErr
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	l.Info("i")
+	l.Error("e")
+	expected := "INFO:  2017/03/09 14:05:07 i\nERROR: 2017/03/09 14:05:07 e\n"
+	if buf.String() != expected {
+		t.Errorf("expected: %q, got: %q", expected, buf.String())
+	}
+	if len(errs) != 0 {
+		t.Errorf("console errors should not be reported, got: %v", errs)
+	}
+}
+
+func TestMultiWriterIsolation(t *testing.T) {
+	buf := &bytes.Buffer{}
+	m := newMultiWriter(failingWriter{}, buf)
+	n, err := m.Write([]byte("x"))
+	if n != 1 || err == nil || err.Error() != "disk full" {
+		t.Errorf("expected first error, got: %d, %v", n, err)
+	}
+	if buf.String() != "x" {
+		t.Errorf("expected write after failing writer, got: %q", buf.String())
+	}
+}
+
+func TestWithoutStorageLevelPrefix(t *testing.T) {
+	buf := &bytes.Buffer{}
+	stdout, stderr := captureConsole(t, func() {
+		l, err := New(buf, "info", true, WithClock(testNow), WithoutStorageLevelPrefix())
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		l.Info("i")
+		l.Error("e")
+	})
+
+	if expected := "2017/03/09 14:05:07 i\n2017/03/09 14:05:07 e\n"; buf.String() != expected {
+		t.Errorf("storage expected: %q, got: %q", expected, buf.String())
+	}
+	if expected := "INFO:  2017/03/09 14:05:07 i\n"; stdout != expected {
+		t.Errorf("stdout expected: %q, got: %q", expected, stdout)
+	}
+	if expected := "ERROR: 2017/03/09 14:05:07 e\n"; stderr != expected {
+		t.Errorf("stderr expected: %q, got: %q", expected, stderr)
+	}
+}
+
+func TestWithColor(t *testing.T) {
+	buf := &bytes.Buffer{}
+	stdout, stderr := captureConsole(t, func() {
+		l, err := New(buf, "info", true, WithClock(testNow), WithColor())
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		l.Info("line 1\nline 2")
+		l.Warnw("w", Str("k", "v"))
+	})
+
+	expected := "INFO:  2017/03/09 14:05:07 line 1\nline 2\nWARN:  2017/03/09 14:05:07 w k=v\n"
+	if buf.String() != expected {
+		t.Errorf("storage expected: %q, got: %q", expected, buf.String())
+	}
+	if expected := "\x1b[32mINFO:\x1b[0m  2017/03/09 14:05:07 line 1\nline 2\n"; stdout != expected {
+		t.Errorf("stdout expected: %q, got: %q", expected, stdout)
+	}
+	if expected := "\x1b[33mWARN:\x1b[0m  2017/03/09 14:05:07 w k=v\n"; stderr != expected {
+		t.Errorf("stderr expected: %q, got: %q", expected, stderr)
+	}
+}
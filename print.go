@@ -0,0 +1,132 @@
+//
+// Copyright 2017 Radovan Vrždiak. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package clog
+
+import (
+	"fmt"
+	"strings"
+)
+
+// WithPrintLevel sets level of messages written by Print, Printf and Println. Default is InfoLevel.
+// Only ErrorLevel, WarnLevel, InfoLevel and DebugLevel are accepted, other levels are ignored.
+func WithPrintLevel(level Level) Option {
+	return func(l *logger) {
+		if level >= ErrorLevel && level <= DebugLevel {
+			l.printLevel = level
+		}
+	}
+}
+
+// Print is for messages at print level (see WithPrintLevel), arguments are handled like in fmt.Print.
+// It eases migration from standard library log package.
+func (l *logger) Print(msg ...interface{}) {
+	if lv, out := l.printOutput(); out != nil {
+		l.print(lv, out, l.compose(msg...))
+	}
+}
+
+// Printf is for formatted messages at print level (see WithPrintLevel).
+func (l *logger) Printf(format string, msg ...interface{}) {
+	if lv, out := l.printOutput(); out != nil {
+		l.print(lv, out, l.composef(format, msg...))
+	}
+}
+
+// Println is for messages at print level (see WithPrintLevel), arguments are handled like in fmt.Println
+// (spaces are always added between operands) but trailing newline is not written twice.
+func (l *logger) Println(msg ...interface{}) {
+	if lv, out := l.printOutput(); out != nil {
+		l.print(lv, out, l.composeln(msg...))
+	}
+}
+
+// Log is for messages at level computed at run time, arguments are handled like in fmt.Print.
+// Messages of levels which are not enabled and of invalid levels are discarded.
+// FatalLevel messages are written like by Fatal but Log never exits.
+func (l *logger) Log(level Level, msg ...interface{}) {
+	if l == nil || !l.levelEnabled(level) || l.logOutput(level) == nil {
+		return // Don't log at lower levels.
+	}
+	l.print(level, l.logOutput(level), l.compose(msg...))
+}
+
+// Logf is for formatted messages at level computed at run time, see Log.
+func (l *logger) Logf(level Level, format string, msg ...interface{}) {
+	if l == nil || !l.levelEnabled(level) || l.logOutput(level) == nil {
+		return // Don't log at lower levels.
+	}
+	l.print(level, l.logOutput(level), l.composef(format, msg...))
+}
+
+// logOutput returns output of level given to Log, the fatal output for FatalLevel.
+func (l *logger) logOutput(lv Level) *output {
+	if lv == FatalLevel {
+		return l.fatal
+	}
+	return l.outputFor(lv)
+}
+
+// Debugln is for debug messages with operands formatted like by fmt.Sprintln.
+func (l *logger) Debugln(msg ...interface{}) {
+	if l == nil || !l.levelEnabled(DebugLevel) || l.debug == nil {
+		return // Don't log at lower levels.
+	}
+	l.print(DebugLevel, l.debug, l.composeln(msg...))
+}
+
+// Infoln is for info messages with operands formatted like by fmt.Sprintln.
+func (l *logger) Infoln(msg ...interface{}) {
+	if l == nil || !l.levelEnabled(InfoLevel) || l.info == nil {
+		return // Don't log at lower levels.
+	}
+	l.print(InfoLevel, l.info, l.composeln(msg...))
+}
+
+// Warnln is for warning messages with operands formatted like by fmt.Sprintln.
+func (l *logger) Warnln(msg ...interface{}) {
+	if l == nil || !l.levelEnabled(WarnLevel) || l.warn == nil {
+		return // Don't log at lower levels.
+	}
+	l.print(WarnLevel, l.warn, l.composeln(msg...))
+}
+
+// Errorln is for error messages with operands formatted like by fmt.Sprintln.
+func (l *logger) Errorln(msg ...interface{}) {
+	if l == nil || !l.levelEnabled(ErrorLevel) || l.error == nil {
+		return // Don't log at lower levels.
+	}
+	l.print(ErrorLevel, l.error, l.composeln(msg...))
+}
+
+// Fatalln is for fatal error messages with operands formatted like by fmt.Sprintln.
+func (l *logger) Fatalln(msg ...interface{}) {
+	if l == nil || l.fatal == nil {
+		return // Don't log at disabled level.
+	}
+	l.exitFatal(l.composeln(msg...))
+}
+
+// printOutput returns print level and its output, nil output if the level is not enabled.
+func (l *logger) printOutput() (Level, *output) {
+	if l == nil {
+		return InvalidLevel, nil
+	}
+
+	lv := l.printLevel
+	if lv == InvalidLevel {
+		lv = InfoLevel
+	}
+	if !l.levelEnabled(lv) {
+		return lv, nil // Don't log at lower levels.
+	}
+	return lv, l.outputFor(lv)
+}
+
+// composeln prepares full log message with operands formatted like by fmt.Sprintln.
+func (l *logger) composeln(msg ...interface{}) entry {
+	s := strings.TrimSuffix(fmt.Sprintln(msg...), "\n")
+	return entry{prefix: l.prefix, pid: l.pid(), goid: l.goid(), caller: l.caller(), name: l.name, msg: l.message(s), fields: l.allFields()}
+}
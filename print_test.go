@@ -0,0 +1,159 @@
+package clog
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestPrint(t *testing.T) {
+	buf := &bytes.Buffer{}
+	l, err := New(buf, "info", false, WithClock(testNow))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	l.Print("a", "b", 1, 2)
+	l.Printf("n=%d", 5)
+	l.Println("a", "b", 1, 2)
+	expected := "INFO:  2017/03/09 14:05:07 ab1 2\n" +
+		"INFO:  2017/03/09 14:05:07 n=5\n" +
+		"INFO:  2017/03/09 14:05:07 a b 1 2\n"
+	if buf.String() != expected {
+		t.Errorf("expected: %q, got: %q", expected, buf.String())
+	}
+}
+
+func TestPrintLevel(t *testing.T) {
+	buf := &bytes.Buffer{}
+	l, err := New(buf, "warning", false, WithClock(testNow), WithConsole(false), WithPrintLevel(WarnLevel))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	l.Printf("n=%d", 5)
+	if expected := "WARN:  2017/03/09 14:05:07 n=5\n"; buf.String() != expected {
+		t.Errorf("expected: %q, got: %q", expected, buf.String())
+	}
+
+	// default InfoLevel is below threshold
+	buf.Reset()
+	l, err = New(buf, "warning", false, WithPrintLevel(DisabledLevel))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	l.Printf("n=%d", 5)
+	if buf.Len() != 0 {
+		t.Errorf("expected nothing at info print level, got: %q", buf.String())
+	}
+
+	d, err := New(buf, "disabled", false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	d.Printf("n=%d", 5)
+	if buf.Len() != 0 {
+		t.Errorf("disabled: expected nothing, got: %q", buf.String())
+	}
+}
+
+func TestLog(t *testing.T) {
+	buf := &bytes.Buffer{}
+	l, err := New(buf, "info", false, WithClock(testNow), WithConsole(false),
+		WithExitFunc(func(int) { t.Error("unexpected exit") }))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for _, lv := range []Level{InvalidLevel, DisabledLevel, ErrorLevel, WarnLevel, InfoLevel, DebugLevel, Level(99)} {
+		l.Log(lv, "level ", lv)
+		l.Logf(lv, "level %d", lv)
+	}
+	expected := "FATAL: 2017/03/09 14:05:07 level disabled\n" +
+		"FATAL: 2017/03/09 14:05:07 level 1\n" +
+		"ERROR: 2017/03/09 14:05:07 level error\n" +
+		"ERROR: 2017/03/09 14:05:07 level 2\n" +
+		"WARN:  2017/03/09 14:05:07 level warning\n" +
+		"WARN:  2017/03/09 14:05:07 level 3\n" +
+		"INFO:  2017/03/09 14:05:07 level info\n" +
+		"INFO:  2017/03/09 14:05:07 level 4\n"
+	if buf.String() != expected {
+		t.Errorf("expected: %q, got: %q", expected, buf.String())
+	}
+}
+
+func TestLogFatalDisabled(t *testing.T) {
+	buf := &bytes.Buffer{}
+	l, err := New(buf, "disabled", false, WithFlags(0), WithConsole(false),
+		WithExitFunc(func(int) { t.Error("unexpected exit") }))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	l.Log(FatalLevel, "fatal")
+	l.Logf(ErrorLevel, "error %d", 1)
+	if expected := "FATAL: fatal\n"; buf.String() != expected {
+		t.Errorf("expected: %q, got: %q", expected, buf.String())
+	}
+}
+
+func TestInfoln(t *testing.T) {
+	buf := &bytes.Buffer{}
+	l, err := New(buf, "info", false, WithConsole(false))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	internal(l).flags = 0
+
+	l.Info("a", "b")
+	l.Infoln("a", "b")
+	l.Infoln("count:", 5)
+	l.Debugln("hidden")
+	l.Warnln("a", 1, "b")
+	l.Errorln("x")
+	expected := "INFO:  ab\n" +
+		"INFO:  a b\n" +
+		"INFO:  count: 5\n" +
+		"WARN:  a 1 b\n" +
+		"ERROR: x\n"
+	if buf.String() != expected {
+		t.Errorf("expected: %q, got: %q", expected, buf.String())
+	}
+}
+
+// TestSpacing pins formatting of operands, Info formats like fmt.Sprint and Infoln like fmt.Sprintln.
+func TestSpacing(t *testing.T) {
+	tests := []struct {
+		msg    []interface{}
+		info   string
+		infoln string
+	}{
+		{[]interface{}{"a", "b"}, "ab", "a b"},
+		{[]interface{}{"count:", 5}, "count:5", "count: 5"},
+		{[]interface{}{5, "items"}, "5items", "5 items"},
+		{[]interface{}{1, 2}, "1 2", "1 2"},
+		{[]interface{}{1, 2.5, true}, "1 2.5 true", "1 2.5 true"},
+		{[]interface{}{"a", 1, 2, "b"}, "a1 2b", "a 1 2 b"},
+		{[]interface{}{"line\n"}, "line", "line"}, // trailing newline is not doubled
+		{[]interface{}{}, "", ""},
+	}
+
+	for _, tt := range tests {
+		buf := &bytes.Buffer{}
+		l, err := New(buf, "info", false, WithConsole(false))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		internal(l).flags = 0
+
+		l.Info(tt.msg...)
+		if expected := "INFO:  " + tt.info + "\n"; buf.String() != expected {
+			t.Errorf("Info(%q) expected: %q, got: %q", tt.msg, expected, buf.String())
+		}
+
+		buf.Reset()
+		l.Infoln(tt.msg...)
+		if expected := "INFO:  " + tt.infoln + "\n"; buf.String() != expected {
+			t.Errorf("Infoln(%q) expected: %q, got: %q", tt.msg, expected, buf.String())
+		}
+	}
+}
@@ -0,0 +1,71 @@
+//
+// Copyright 2017 Radovan Vrždiak. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package clog
+
+import "regexp"
+
+// redacted replaces sensitive data matched by redaction rules.
+const redacted = "[REDACTED]"
+
+// WithRedaction replaces every occurrence of secrets (e.g. tokens or passwords)
+// by "[REDACTED]" before entries are written. See WithRedactionRegexp.
+func WithRedaction(secrets ...string) Option {
+	return func(l *logger) {
+		for _, s := range secrets {
+			if s != "" {
+				l.redact = append(l.redact, regexp.MustCompile(regexp.QuoteMeta(s)))
+			}
+		}
+	}
+}
+
+// WithRedactionRegexp replaces every match of patterns by "[REDACTED]" before entries are written,
+// e.g. regexp.MustCompile(`password=\S+`).
+// Rules apply to messages of all levels and formats and to string and error values of fields.
+// Values of other types are not inspected.
+func WithRedactionRegexp(patterns ...*regexp.Regexp) Option {
+	return func(l *logger) {
+		for _, re := range patterns {
+			if re != nil {
+				l.redact = append(l.redact, re)
+			}
+		}
+	}
+}
+
+// redactString replaces data matched by redaction rules in s.
+func (l *logger) redactString(s string) string {
+	for _, re := range l.redact {
+		s = re.ReplaceAllLiteralString(s, redacted)
+	}
+	return s
+}
+
+// redactFields returns copy of fields with redacted string and error values.
+// fields are returned as they are if there are no rules.
+func (l *logger) redactFields(fields []Field) []Field {
+	if len(l.redact) == 0 || len(fields) == 0 {
+		return fields
+	}
+
+	out := make([]Field, len(fields))
+	for i, f := range fields {
+		switch v := f.Value.(type) {
+		case string:
+			f.Value = l.redactString(v)
+		case error:
+			f.Value = l.redactString(v.Error())
+		case []string:
+			r := make([]string, len(v))
+			for j, s := range v {
+				r[j] = l.redactString(s)
+			}
+			f.Value = r
+		}
+		out[i] = f
+	}
+	return out
+}
@@ -0,0 +1,50 @@
+package clog
+
+import (
+	"bytes"
+	"errors"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestRedaction(t *testing.T) {
+	for _, format := range []Format{TextFormat, JSONFormat} {
+		buf := &bytes.Buffer{}
+		l, err := New(buf, "info", false, WithClock(testNow), WithFormat(format),
+			WithRedaction("s3cr3t", ""),
+			WithRedactionRegexp(regexp.MustCompile(`password=\S+`)))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		l.Info("token s3cr3t used")
+		l.Errorf("login password=%s failed", "hunter2")
+		l.Infow("call", Str("auth", "Bearer s3cr3t"), Err(errors.New("bad password=hunter2")))
+		l.ErrorErr(errors.New("wrap: s3cr3t"), "failed")
+		l.WithFields(map[string]interface{}{"key": "s3cr3t"}).Warn("child")
+		l.StdLogger(InfoLevel).Print("std s3cr3t")
+
+		out := buf.String()
+		if strings.Contains(out, "s3cr3t") || strings.Contains(out, "hunter2") {
+			t.Errorf("format %d: secret reached writer:\n%s", format, out)
+		}
+		if n := strings.Count(out, "[REDACTED]"); n != 7 {
+			t.Errorf("format %d: expected 7 redactions, got %d:\n%s", format, n, out)
+		}
+	}
+}
+
+func TestRedactionText(t *testing.T) {
+	buf := &bytes.Buffer{}
+	l, err := New(buf, "info", false, WithClock(testNow), WithRedaction("s3cr3t"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	l.Infow("token s3cr3t", Str("auth", "s3cr3t"), Int("n", 1))
+	expected := "INFO:  2017/03/09 14:05:07 token [REDACTED] auth=[REDACTED] n=1\n"
+	if buf.String() != expected {
+		t.Errorf("expected: %q, got: %q", expected, buf.String())
+	}
+}
@@ -0,0 +1,33 @@
+//
+// Copyright 2017 Radovan Vrždiak. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package clog
+
+// Reset returns l to configuration it had when it was created (or cloned, see Clone):
+//   - level changed by SetLevel or WithLevel is restored,
+//   - overrides of EnableLevel and DisableLevel are removed,
+//   - storage writer replaced by SetOutput is restored (the original writer must still be usable),
+//   - fields of SetFields are removed,
+//   - Counts start from zero.
+//
+// Options given to the constructor (verbosity, formats, fields of WithFields, ...) are not affected,
+// neither are subsystem levels, sequence numbers, state of WithDedup and WithSampler or closed log (see Close).
+// Level, storage writer and counts are shared with Named children, so Reset affects them too.
+// It is intended mainly for tests reusing package level logger.
+func (l *logger) Reset() {
+	if l == nil || l.fatal == nil {
+		return
+	}
+
+	l.level.reset()
+	if l.storage != nil {
+		l.storage.reset()
+		l.w = l.storage.unwrapped()
+	}
+	l.ClearFields()
+	if l.counts != nil {
+		l.counts.reset()
+	}
+}
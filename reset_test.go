@@ -0,0 +1,41 @@
+package clog
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestReset(t *testing.T) {
+	buf := &bytes.Buffer{}
+	l, err := New(buf, "info", false, WithClock(testNow), WithConsole(false))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	child := l.Named("db")
+
+	other := &bytes.Buffer{}
+	if err := l.SetLevel(ErrorLevel); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	l.SetOutput(other)
+	l.SetFields(Int("conn", 7))
+	l.Error("changed")
+	if expected := "ERROR: 2017/03/09 14:05:07 changed conn=7\n"; other.String() != expected || buf.Len() != 0 {
+		t.Fatalf("expected: %q in new output, got: %q, %q", expected, other.String(), buf.String())
+	}
+
+	l.Reset()
+	l.Info("restored")
+	child.Info("child")
+	expected := "INFO:  2017/03/09 14:05:07 restored\n" +
+		"INFO:  2017/03/09 14:05:07 db: child\n"
+	if buf.String() != expected {
+		t.Errorf("expected: %q, got: %q", expected, buf.String())
+	}
+	if n := len(other.String()); n != len("ERROR: 2017/03/09 14:05:07 changed conn=7\n") {
+		t.Errorf("expected nothing more in replaced output, got: %q", other.String())
+	}
+	if c := l.Counts(); c[InfoLevel] != 2 || c[ErrorLevel] != 0 {
+		t.Errorf("expected counts since reset, got: %v", c)
+	}
+}
@@ -0,0 +1,78 @@
+//
+// Copyright 2017 Radovan Vrždiak. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package clog
+
+import (
+	"io"
+	"strings"
+	"sync"
+)
+
+// RingSink is an io.Writer keeping the last N log lines in memory,
+// e.g. for /debug/log HTTP handler. Oldest lines are evicted past capacity.
+// Every Write call is stored as one line (Logger writes whole line per call).
+// It is safe for concurrent use.
+type RingSink struct {
+	mu    sync.Mutex
+	lines []string
+	next  int // index of next write
+	full  bool
+}
+
+// NewRingSink creates RingSink holding at most n lines (at least 1).
+// It can be used as a writer of New or as one of NewMulti sinks.
+func NewRingSink(n int) *RingSink {
+	if n < 1 {
+		n = 1
+	}
+	return &RingSink{lines: make([]string, n)}
+}
+
+// Write stores p as one line, trailing newline is removed.
+func (r *RingSink) Write(p []byte) (int, error) {
+	s := strings.TrimSuffix(string(p), "\n")
+
+	r.mu.Lock()
+	r.lines[r.next] = s
+	r.next++
+	if r.next == len(r.lines) {
+		r.next = 0
+		r.full = true
+	}
+	r.mu.Unlock()
+
+	return len(p), nil
+}
+
+// Lines returns copy of stored lines, oldest first.
+func (r *RingSink) Lines() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if !r.full {
+		return append([]string(nil), r.lines[:r.next]...)
+	}
+
+	out := make([]string, 0, len(r.lines))
+	out = append(out, r.lines[r.next:]...)
+	return append(out, r.lines[:r.next]...)
+}
+
+// DumpTo writes stored lines (oldest first, each terminated by newline) to w
+// and returns number of written bytes. It is intended for crash reports,
+// e.g. called from recover handler to attach recent log context.
+// Lines are copied under lock so w may be written while logging continues.
+func (r *RingSink) DumpTo(w io.Writer) (int64, error) {
+	var total int64
+	for _, line := range r.Lines() {
+		n, err := io.WriteString(w, line+"\n")
+		total += int64(n)
+		if err != nil {
+			return total, err
+		}
+	}
+	return total, nil
+}
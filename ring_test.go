@@ -0,0 +1,88 @@
+package clog
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestRingSink(t *testing.T) {
+	r := NewRingSink(3)
+	if n := len(r.Lines()); n != 0 {
+		t.Errorf("empty ring expected 0 lines, got %d", n)
+	}
+
+	for i := 1; i <= 2; i++ {
+		fmt.Fprintf(r, "line %d\n", i)
+	}
+	if got := strings.Join(r.Lines(), ","); got != "line 1,line 2" {
+		t.Errorf("expected: %q, got: %q", "line 1,line 2", got)
+	}
+
+	for i := 3; i <= 7; i++ {
+		fmt.Fprintf(r, "line %d\n", i)
+	}
+	if got := strings.Join(r.Lines(), ","); got != "line 5,line 6,line 7" {
+		t.Errorf("expected: %q, got: %q", "line 5,line 6,line 7", got)
+	}
+}
+
+func TestRingSinkWithLogger(t *testing.T) {
+	file := &strings.Builder{}
+	ring := NewRingSink(10)
+	l, err := NewMulti([]Sink{
+		{Writer: file, Level: ErrorLevel},
+		{Writer: ring, Level: DebugLevel},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				l.Debugf("goroutine %d message %d", i, j)
+				_ = ring.Lines()
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	lines := ring.Lines()
+	if len(lines) != 10 {
+		t.Fatalf("expected 10 lines, got %d", len(lines))
+	}
+	for _, line := range lines {
+		if !strings.HasPrefix(line, "DEBUG: ") {
+			t.Errorf("unexpected line: %q", line)
+		}
+	}
+}
+
+func TestRingSinkDumpTo(t *testing.T) {
+	r := NewRingSink(3)
+	for i := 1; i <= 10; i++ {
+		fmt.Fprintf(r, "line %d\n", i)
+	}
+
+	buf := &strings.Builder{}
+	n, err := r.DumpTo(buf)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := "line 8\nline 9\nline 10\n"
+	if buf.String() != expected {
+		t.Errorf("expected: %q, got: %q", expected, buf.String())
+	}
+	if n != int64(len(expected)) {
+		t.Errorf("expected %d bytes, got %d", len(expected), n)
+	}
+
+	if _, err := r.DumpTo(failingWriter{}); err == nil {
+		t.Error("expected error of failing writer")
+	}
+}
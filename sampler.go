@@ -0,0 +1,75 @@
+//
+// Copyright 2017 Radovan Vrždiak. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package clog
+
+import (
+	"sync"
+	"time"
+)
+
+// WithSampler limits the volume of warning, info and debug messages.
+// Within each second the first messages of each level are logged
+// and after that only every thereafter-th message (none if thereafter <= 0).
+// Counters are reset every second.
+// Error and fatal messages are never sampled.
+// Sampling is disabled by default or if first < 0.
+func WithSampler(first, thereafter int) Option {
+	return func(l *logger) {
+		if first < 0 {
+			l.sampler = nil
+			return
+		}
+		l.sampler = &sampler{
+			first:      first,
+			thereafter: thereafter,
+			counts:     make(map[Level]*sampleCount),
+		}
+	}
+}
+
+// sampler counts messages per level in one-second windows.
+type sampler struct {
+	first      int
+	thereafter int
+	mu         sync.Mutex
+	counts     map[Level]*sampleCount
+}
+
+type sampleCount struct {
+	tick int64 // current window (unix seconds)
+	n    int   // messages seen in current window
+}
+
+// allow reports whether message of level lv occurring at time now should be logged.
+func (s *sampler) allow(lv Level, now time.Time) bool {
+	if lv <= ErrorLevel {
+		return true // errors (and fatal) are never sampled
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	c, ok := s.counts[lv]
+	if !ok {
+		c = &sampleCount{}
+		s.counts[lv] = c
+	}
+
+	tick := now.Unix()
+	if c.tick != tick {
+		c.tick = tick
+		c.n = 0
+	}
+	c.n++
+
+	if c.n <= s.first {
+		return true
+	}
+	if s.thereafter <= 0 {
+		return false
+	}
+	return (c.n-s.first)%s.thereafter == 0
+}
@@ -0,0 +1,61 @@
+package clog
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSampler(t *testing.T) {
+	now := time.Date(2017, 1, 1, 0, 0, 0, 0, time.UTC)
+	buf := &bytes.Buffer{}
+	clock := func() time.Time { return now }
+	l, err := New(buf, "debug", false, WithSampler(2, 3), WithClock(clock))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// first 2 pass, then every 3rd: messages 1, 2, 5, 8
+	for i := 1; i <= 10; i++ {
+		l.Debugf("debug %d", i)
+	}
+	// errors are never sampled
+	for i := 1; i <= 10; i++ {
+		l.Errorf("error %d", i)
+	}
+	// new second resets counters
+	now = now.Add(time.Second)
+	l.Debugf("debug %d", 11)
+
+	var got []string
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		if strings.HasPrefix(line, "DEBUG: ") {
+			got = append(got, line[strings.LastIndex(line, " ")+1:])
+		}
+	}
+	expected := []string{"1", "2", "5", "8", "11"}
+	if strings.Join(got, ",") != strings.Join(expected, ",") {
+		t.Errorf("sampled debug messages expected: %v, got: %v", expected, got)
+	}
+	if n := strings.Count(buf.String(), "ERROR: "); n != 10 {
+		t.Errorf("expected 10 error messages, got %d", n)
+	}
+}
+
+func TestSamplerDropAfterFirst(t *testing.T) {
+	s := &sampler{first: 1, thereafter: 0, counts: make(map[Level]*sampleCount)}
+	now := time.Date(2017, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	if !s.allow(InfoLevel, now) {
+		t.Error("first message should be allowed")
+	}
+	for i := 0; i < 5; i++ {
+		if s.allow(InfoLevel, now) {
+			t.Errorf("message %d should be dropped", i+2)
+		}
+	}
+	if !s.allow(WarnLevel, now) {
+		t.Error("levels should be sampled independently")
+	}
+}
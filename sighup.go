@@ -0,0 +1,59 @@
+//
+// Copyright 2017 Radovan Vrždiak. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build !windows && !plan9
+// +build !windows,!plan9
+
+package clog
+
+import (
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+)
+
+// HandleSIGHUP reopens log file fname (see OpenFile) on SIGHUP and sets it as output of l (see SetOutput).
+// It supports external log rotation (e.g. logrotate) which renames the file and sends SIGHUP to the process.
+// Returned cancel func uninstalls the handler, the current output stays in use.
+//
+// Previously reopened files are closed after the swap, the file given to New is left to the caller.
+// If reopening fails, error is logged and the current output stays in use.
+// HandleSIGHUP is not needed when the log file is not rotated by an external tool.
+// It is not available on Windows which has no SIGHUP.
+func HandleSIGHUP(l Logger, fname string) (cancel func()) {
+	c := make(chan os.Signal, 1)
+	signal.Notify(c, syscall.SIGHUP)
+	done := make(chan struct{})
+
+	go func() {
+		var opened *os.File // file opened by handler; the original one is owned by caller
+		for {
+			select {
+			case <-c:
+				fd, err := OpenFile(fname)
+				if err != nil {
+					l.Errorf("reopen log file on SIGHUP: %v", err)
+					continue
+				}
+				l.SetOutput(fd)
+				if opened != nil {
+					opened.Close()
+				}
+				opened = fd
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			signal.Stop(c)
+			close(done)
+		})
+	}
+}
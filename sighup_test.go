@@ -0,0 +1,71 @@
+//go:build !windows && !plan9
+// +build !windows,!plan9
+
+package clog
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestHandleSIGHUP(t *testing.T) {
+	tmp, err := ioutil.TempDir("", "clog-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmp)
+
+	fname := filepath.Join(tmp, "app.log")
+	fd, err := OpenFile(fname)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fd.Close()
+
+	l, err := New(fd, "info", false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	cancel := HandleSIGHUP(l, fname)
+	defer cancel()
+
+	l.Info("before rotation")
+	rotated := fname + ".1"
+	if err := os.Rename(fname, rotated); err != nil {
+		t.Fatal(err)
+	}
+	if err := syscall.Kill(os.Getpid(), syscall.SIGHUP); err != nil {
+		t.Fatal(err)
+	}
+
+	// handler runs asynchronously, wait until the reopened file receives messages
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		l.Info("after rotation")
+		data, _ := ioutil.ReadFile(fname)
+		if strings.Contains(string(data), "after rotation") {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("log file was not reopened on SIGHUP")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	data, err := ioutil.ReadFile(rotated)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), "before rotation") {
+		t.Errorf("rotated file should contain old messages, got: %q", data)
+	}
+	data, _ = ioutil.ReadFile(fname)
+	if strings.Contains(string(data), "before rotation") {
+		t.Errorf("new file should not contain old messages, got: %q", data)
+	}
+}
@@ -0,0 +1,78 @@
+//
+// Copyright 2017 Radovan Vrždiak. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package clog
+
+import (
+	"fmt"
+	"io"
+)
+
+// Sink is a log destination with its own level threshold.
+// Writer receives messages of Level and all more severe levels.
+// Fatal messages are written to every sink.
+type Sink struct {
+	Writer io.Writer
+	Level  Level
+}
+
+// NewMulti creates new Logger writing to multiple sinks.
+// Every message is written to each sink whose Level it meets.
+// Unlike New there is no implicit console output, add os.Stderr or os.Stdout sink if needed.
+// The first sink is the primary storage replaced by SetOutput.
+// It returns nil Logger and error if any sink is not valid.
+func NewMulti(sinks []Sink, opts ...Option) (Logger, error) {
+	l := newLogger(opts)
+
+	if len(sinks) == 0 {
+		return nil, fmt.Errorf("no sinks specified")
+	}
+
+	level := DisabledLevel
+	for i, s := range sinks {
+		if s.Writer == nil {
+			return nil, fmt.Errorf("sink %d: nil writer", i)
+		}
+		if err := s.Level.Validate(); err != nil {
+			return nil, fmt.Errorf("sink %d: %v", i, err)
+		}
+		if s.Level > level {
+			level = s.Level // logger level is the most verbose one
+		}
+		l.writers = append(l.writers, s.Writer)
+	}
+
+	l.level = newLevelVar(level)
+	targets := make([]io.Writer, 0, len(sinks))
+	for _, s := range sinks {
+		targets = append(targets, s.Writer)
+	}
+
+	// first sink is the primary storage which can be replaced by SetOutput
+	sinks = append([]Sink(nil), sinks...)
+	for _, w := range l.extra {
+		sinks = append(sinks, Sink{Writer: w, Level: level})
+		l.writers = append(l.writers, w)
+	}
+	l.storage = newSwitchWriter(sinks[0].Writer, nil)
+	l.writers[0] = l.storage
+	sinks[0].Writer = l.storage
+	for i := range sinks {
+		sinks[i].Writer = l.reportErrors(sinks[i].Writer)
+	}
+
+	l.setup(func(lv Level) io.Writer {
+		ws := make([]io.Writer, 0, len(sinks))
+		for _, s := range sinks {
+			if lv == DisabledLevel || s.Level >= lv {
+				ws = append(ws, s.Writer)
+			}
+		}
+		return newMultiWriter(ws...)
+	})
+	l.logStartup(targets...)
+
+	return l.optimized(), nil
+}
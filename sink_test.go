@@ -0,0 +1,63 @@
+package clog
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestNewMulti(t *testing.T) {
+	file, stderr, ring := &bytes.Buffer{}, &bytes.Buffer{}, &bytes.Buffer{}
+	l, err := NewMulti([]Sink{
+		{Writer: file, Level: ErrorLevel},
+		{Writer: stderr, Level: WarnLevel},
+		{Writer: ring, Level: DebugLevel},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	l.Debug("d")
+	l.Info("i")
+	l.Warn("w")
+	l.Error("e")
+
+	tests := []struct {
+		name     string
+		buf      *bytes.Buffer
+		expected []string
+	}{
+		{"file", file, []string{"ERROR"}},
+		{"stderr", stderr, []string{"WARN", "ERROR"}},
+		{"ring", ring, []string{"DEBUG", "INFO", "WARN", "ERROR"}},
+	}
+	for _, tt := range tests {
+		lines := strings.Split(strings.TrimSpace(tt.buf.String()), "\n")
+		if len(lines) != len(tt.expected) {
+			t.Errorf("%s: expected %d lines, got %d:\n%s", tt.name, len(tt.expected), len(lines), tt.buf.String())
+			continue
+		}
+		for i, p := range tt.expected {
+			if !strings.HasPrefix(lines[i], p+":") {
+				t.Errorf("%s: line %d expected prefix %q, got: %q", tt.name, i, p, lines[i])
+			}
+		}
+	}
+}
+
+func TestNewMultiInvalid(t *testing.T) {
+	tests := map[string][]Sink{
+		"no sinks":      nil,
+		"nil writer":    {{Writer: nil, Level: InfoLevel}},
+		"invalid level": {{Writer: &bytes.Buffer{}}},
+	}
+	for name, sinks := range tests {
+		l, err := NewMulti(sinks)
+		if err == nil {
+			t.Errorf("%s: expected error, got nil", name)
+		}
+		if l != nil {
+			t.Errorf("%s: expected nil Logger on error, got %#v", name, l)
+		}
+	}
+}
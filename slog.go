@@ -0,0 +1,148 @@
+//
+// Copyright 2017 Radovan Vrždiak. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build go1.21
+// +build go1.21
+
+package clog
+
+import (
+	"context"
+	"log/slog"
+	"runtime"
+)
+
+// slogHandler is slog.Handler writing records to Logger.
+type slogHandler struct {
+	inner  Logger
+	fields []Field // attributes of WithAttrs
+	group  string  // key prefix of WithGroup, e.g. "req."
+}
+
+// NewSlogHandler returns slog.Handler writing records to inner so clog can be used as slog backend:
+//
+//	slog.New(clog.NewSlogHandler(l))
+//
+// Levels between slog.LevelDebug, LevelInfo, LevelWarn and LevelError are rounded down,
+// e.g. slog.LevelInfo+2 is written as InfoLevel.
+// Attributes are written as fields, keys in groups are qualified by group names separated by dot,
+// e.g. "req.method". Time of record is ignored, timestamp is added by inner.
+func NewSlogHandler(inner Logger) slog.Handler {
+	return &slogHandler{inner: inner}
+}
+
+// SlogLogger returns *slog.Logger writing to inner, e.g. for dependencies accepting *slog.Logger.
+// Levels are filtered by inner so slog's Enabled checks follow level of inner (including SetLevel),
+// see NewSlogHandler for mapping of levels and attributes.
+func SlogLogger(inner Logger) *slog.Logger {
+	return slog.New(NewSlogHandler(inner))
+}
+
+// slogLevel returns Level of slog level lv.
+func slogLevel(lv slog.Level) Level {
+	switch {
+	case lv >= slog.LevelError:
+		return ErrorLevel
+	case lv >= slog.LevelWarn:
+		return WarnLevel
+	case lv >= slog.LevelInfo:
+		return InfoLevel
+	}
+	return DebugLevel
+}
+
+func (h *slogHandler) Enabled(_ context.Context, lv slog.Level) bool {
+	return h.inner.IsEnabled(slogLevel(lv))
+}
+
+func (h *slogHandler) Handle(_ context.Context, r slog.Record) error {
+	fields := make([]Field, len(h.fields), len(h.fields)+r.NumAttrs())
+	copy(fields, h.fields)
+	r.Attrs(func(a slog.Attr) bool {
+		fields = appendAttr(fields, h.group, a)
+		return true
+	})
+
+	lv := slogLevel(r.Level)
+	x := internal(h.inner)
+	if x == nil {
+		h.foreign(lv, r.Message, fields)
+		return nil
+	}
+	if !x.IsEnabled(lv) {
+		return nil // Don't log at lower levels.
+	}
+
+	e := entry{prefix: x.prefix, pid: x.pid(), goid: x.goid(), caller: x.slogCaller(r.PC), name: x.name,
+		msg: x.message(r.Message), fields: appendFieldList(x.allFields(), fields)}
+	x.print(lv, x.outputFor(lv), e)
+	return nil
+}
+
+// foreign writes message to inner which is not implemented by this package.
+func (h *slogHandler) foreign(lv Level, msg string, fields []Field) {
+	switch lv {
+	case ErrorLevel:
+		h.inner.Errorw(msg, fields...)
+	case WarnLevel:
+		h.inner.Warnw(msg, fields...)
+	case InfoLevel:
+		h.inner.Infow(msg, fields...)
+	default:
+		h.inner.Debugw(msg, fields...)
+	}
+}
+
+func (h *slogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	c := *h
+	c.fields = make([]Field, len(h.fields), len(h.fields)+len(attrs))
+	copy(c.fields, h.fields)
+	for _, a := range attrs {
+		c.fields = appendAttr(c.fields, h.group, a)
+	}
+	return &c
+}
+
+func (h *slogHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	c := *h
+	c.group += name + "."
+	return &c
+}
+
+// appendAttr appends a to fields, group is prefix of keys.
+// Groups are flattened, empty attributes and groups are ignored.
+func appendAttr(fields []Field, group string, a slog.Attr) []Field {
+	a.Value = a.Value.Resolve()
+	if a.Equal(slog.Attr{}) {
+		return fields
+	}
+	if a.Value.Kind() != slog.KindGroup {
+		return append(fields, Field{Key: group + a.Key, Value: a.Value.Any()})
+	}
+
+	if a.Key != "" {
+		group += a.Key + "."
+	}
+	for _, ga := range a.Value.Group() {
+		fields = appendAttr(fields, group, ga)
+	}
+	return fields
+}
+
+// slogCaller returns caller info of code at pc of slog record, pc 0 means unknown caller.
+func (l *logger) slogCaller(pc uintptr) string {
+	if pc == 0 || !l.callerEnabled() {
+		return ""
+	}
+
+	frame, _ := runtime.CallersFrames([]uintptr{pc}).Next()
+	if frame.File == "" {
+		return l.formatCaller(frame.PC, "???", 0, false)
+	}
+	return l.formatCaller(frame.PC, frame.File, frame.Line, true)
+}
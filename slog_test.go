@@ -0,0 +1,76 @@
+//go:build go1.21
+// +build go1.21
+
+package clog
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"runtime"
+	"testing"
+)
+
+func TestSlogHandler(t *testing.T) {
+	l, buf := NewTestLogger(InfoLevel)
+	internal(l).flags = 0
+	s := slog.New(NewSlogHandler(l.Named("app")))
+
+	s.Info("started", "port", 8080, slog.Group("req", "method", "GET", slog.Group("", "inline", true)))
+	s.With("id", 7).WithGroup("db").Warn("slow", "ms", 30, slog.Group("empty"))
+	s.Log(context.Background(), slog.LevelInfo+2, "info+2")
+	s.Error("failed", "err", fmt.Errorf("timeout"))
+	s.Debug("hidden")
+
+	expected := "INFO:  app: started port=8080 req.method=GET req.inline=true\n" +
+		"WARN:  app: slow id=7 db.ms=30\n" +
+		"INFO:  app: info+2\n" +
+		"ERROR: app: failed err=timeout\n"
+	if buf.String() != expected {
+		t.Errorf("expected: %q, got: %q", expected, buf.String())
+	}
+}
+
+func TestSlogHandlerCaller(t *testing.T) {
+	l, buf := NewTestLogger(DebugLevel, WithCallerPath(ShortPath))
+	internal(l).flags = 0
+	s := slog.New(NewSlogHandler(l))
+
+	_, _, line, _ := runtime.Caller(0)
+	s.Debug("caller")
+	if expected := fmt.Sprintf("DEBUG: [%d] slog_test.go:%d caller\n", os.Getpid(), line+1); buf.String() != expected {
+		t.Errorf("expected: %q, got: %q", expected, buf.String())
+	}
+}
+
+func TestSlogLogger(t *testing.T) {
+	r := &levelRecorder{}
+	l, err := NewWithLevel(r, InfoLevel, false, WithConsole(false))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	s := SlogLogger(l)
+	ctx := context.Background()
+
+	if s.Enabled(ctx, slog.LevelDebug) || !s.Enabled(ctx, slog.LevelInfo) {
+		t.Error("expected slog levels enabled according to InfoLevel")
+	}
+	s.Debug("hidden")
+	s.Info("i")
+	s.Warn("w")
+	s.Error("e")
+
+	if err := l.SetLevel(DebugLevel); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !s.Enabled(ctx, slog.LevelDebug) {
+		t.Error("expected debug enabled after SetLevel")
+	}
+	s.Debug("d")
+
+	expected := []Level{InfoLevel, WarnLevel, ErrorLevel, DebugLevel}
+	if fmt.Sprint(r.levels) != fmt.Sprint(expected) {
+		t.Errorf("expected: %v, got: %v", expected, r.levels)
+	}
+}
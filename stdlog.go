@@ -0,0 +1,85 @@
+//
+// Copyright 2017 Radovan Vrždiak. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package clog
+
+import (
+	"io/ioutil"
+	"log"
+	"os"
+	"strings"
+)
+
+// StdLogger returns standard library logger writing through l at given level.
+// It is handy for libraries requiring *log.Logger.
+// Prefix and flags of returned logger are controlled by clog and should not be changed,
+// level prefix and timestamp are added by l. Caller info is not available.
+// Messages are discarded if level is not enabled in l.
+func (l *logger) StdLogger(level Level) *log.Logger {
+	if l == nil {
+		return log.New(ioutil.Discard, "", 0)
+	}
+	return log.New(stdWriter{l: l, level: level}, "", 0)
+}
+
+// ForLevel returns function writing messages at given level, e.g. for APIs expecting
+// func(args ...interface{}) logging callback. Level and configuration of l are checked
+// at every call, not when the function is created. Caller info refers to caller of the function.
+// Messages are discarded if level is not enabled in l, fatal messages are not supported.
+func (l *logger) ForLevel(level Level) func(msg ...interface{}) {
+	return func(msg ...interface{}) {
+		if l == nil || !l.levelEnabled(level) || l.outputFor(level) == nil {
+			return // Don't log at lower levels.
+		}
+		l.print(level, l.outputFor(level), l.compose(msg...))
+	}
+}
+
+// FromStdLogger creates new Logger writing through std (see log.Logger.Output), e.g. to adopt
+// levels in legacy code holding configured std logger. Destination, prefix and flags of std are preserved,
+// level prefix (e.g. "WARN:  ") is written at the start of the message as std logger has no levels:
+//
+//	app: 2017/03/09 14:05:07 WARN:  disk almost full
+//
+// Timestamp and console output are not added by the Logger unless opts say otherwise.
+// File flags of std (log.Lshortfile) refer to this package, caller info of the Logger shows logging call.
+// Nil std is logger writing to stderr with standard flags.
+// It returns nil Logger and error if level is not valid.
+func FromStdLogger(std *log.Logger, level Level, opts ...Option) (Logger, error) {
+	if std == nil {
+		std = log.New(os.Stderr, "", log.LstdFlags)
+	}
+
+	opts = append([]Option{WithConsole(false), WithFlags(0)}, opts...)
+	return NewWithLevel(stdOutput{std}, level, false, opts...)
+}
+
+// stdOutput writes entries by Output method of std logger.
+type stdOutput struct {
+	std *log.Logger
+}
+
+func (w stdOutput) Write(p []byte) (int, error) {
+	if err := w.std.Output(2, string(p)); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// stdWriter routes lines written by std logger into leveled write path.
+type stdWriter struct {
+	l     *logger
+	level Level
+}
+
+func (w stdWriter) Write(p []byte) (int, error) {
+	out := w.l.outputFor(w.level)
+	if !w.l.levelEnabled(w.level) || out == nil {
+		return len(p), nil // Don't log at lower levels.
+	}
+	w.l.print(w.level, out, entry{prefix: w.l.prefix, pid: w.l.pid(), goid: w.l.goid(), name: w.l.name, msg: w.l.message(strings.TrimSuffix(string(p), "\n")), fields: w.l.allFields()})
+
+	return len(p), nil
+}
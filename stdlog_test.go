@@ -0,0 +1,105 @@
+package clog
+
+import (
+	"bytes"
+	"log"
+	"strings"
+	"testing"
+)
+
+func TestStdLogger(t *testing.T) {
+	buf := &bytes.Buffer{}
+	l, err := New(buf, "info", false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	std := l.StdLogger(InfoLevel)
+	std.Printf("from %s", "library")
+	std.Print("second\n")
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected 2 lines, got %d:\n%s", len(lines), buf.String())
+	}
+	for i, s := range []string{"from library", "second"} {
+		if !strings.HasPrefix(lines[i], "INFO:  ") || !strings.HasSuffix(lines[i], " "+s) {
+			t.Errorf("line %d expected INFO line with %q, got: %q", i, s, lines[i])
+		}
+	}
+
+	buf.Reset()
+	l.StdLogger(DebugLevel).Print("debug is not enabled")
+	l.StdLogger(Level(999)).Print("invalid level")
+	if buf.Len() != 0 {
+		t.Errorf("expected no output, got: %q", buf.String())
+	}
+}
+
+func TestForLevel(t *testing.T) {
+	l, buf := NewTestLogger(InfoLevel)
+	internal(l).flags = 0
+	warn := l.ForLevel(WarnLevel)
+
+	warn("disk ", 90, "%")
+	if buf.String() != "WARN:  disk 90%\n" {
+		t.Errorf("expected: %q, got: %q", "WARN:  disk 90%\n", buf.String())
+	}
+
+	buf.Reset()
+	if err := l.SetLevel(ErrorLevel); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	warn("suppressed")
+	if buf.Len() != 0 {
+		t.Errorf("expected no output after raising level, got: %q", buf.String())
+	}
+
+	if err := l.SetLevel(DebugLevel); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	warn("back")
+	if !strings.HasPrefix(buf.String(), "WARN:  ") || !strings.HasSuffix(buf.String(), " back\n") {
+		t.Errorf("expected warning after dropping level, got: %q", buf.String())
+	}
+	if !strings.Contains(buf.String(), "stdlog_test.go:") {
+		t.Errorf("expected caller of bound function, got: %q", buf.String())
+	}
+}
+
+func TestFromStdLogger(t *testing.T) {
+	buf := &bytes.Buffer{}
+	std := log.New(buf, "app: ", 0)
+	l, err := FromStdLogger(std, WarnLevel)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	l.Info("suppressed")
+	l.Warn("disk almost full")
+	l.Errorf("disk %s", "full")
+	expected := "app: WARN:  disk almost full\n" +
+		"app: ERROR: disk full\n"
+	if buf.String() != expected {
+		t.Errorf("expected: %q, got: %q", expected, buf.String())
+	}
+
+	buf.Reset()
+	if err := l.SetLevel(InfoLevel); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	l.Info("now visible")
+	if expected := "app: INFO:  now visible\n"; buf.String() != expected {
+		t.Errorf("expected: %q, got: %q", expected, buf.String())
+	}
+}
+
+func TestFromStdLoggerInvalidLevel(t *testing.T) {
+	buf := &bytes.Buffer{}
+	for _, lv := range []Level{InvalidLevel, Level(42)} {
+		l, err := FromStdLogger(log.New(buf, "", 0), lv)
+		if err == nil || l != nil {
+			t.Errorf("level %d: expected nil Logger and error, got: %v, %v", lv, l, err)
+		}
+	}
+}
@@ -0,0 +1,104 @@
+//
+// Copyright 2017 Radovan Vrždiak. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package clog
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// subsystems holds level overrides of named loggers, see SetLevelFor.
+var subsystems = struct {
+	mu     sync.RWMutex
+	levels map[string]Level
+	count  int32 // len(levels) for lock-free check of empty map
+}{levels: make(map[string]Level)}
+
+// SetLevelFor overrides level of loggers named name (see Named) and of their children,
+// e.g. SetLevelFor("db", DebugLevel) affects loggers named "db" and "db.conn".
+// Override of the most specific name wins, loggers without override use their own level.
+// InvalidLevel removes the override. Overrides are global for all loggers of the process.
+// Loggers created at DisabledLevel are not affected.
+func SetLevelFor(name string, level Level) error {
+	if level != InvalidLevel {
+		if err := level.Validate(); err != nil {
+			return err
+		}
+	}
+
+	subsystems.mu.Lock()
+	defer subsystems.mu.Unlock()
+
+	if level == InvalidLevel {
+		delete(subsystems.levels, name)
+	} else {
+		subsystems.levels[name] = level
+	}
+	atomic.StoreInt32(&subsystems.count, int32(len(subsystems.levels)))
+
+	return nil
+}
+
+// SetLevelsFromString sets overrides (see SetLevelFor) given as comma separated name=level pairs,
+// e.g. "db=debug,http=info" from environment variable. No override is set if s is not valid.
+func SetLevelsFromString(s string) error {
+	levels := make(map[string]Level)
+	for _, pair := range strings.Split(s, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+
+		i := strings.IndexByte(pair, '=')
+		if i <= 0 {
+			return fmt.Errorf("invalid level override %q, expected name=level", pair)
+		}
+		lv, err := LevelFromString(pair[i+1:])
+		if err != nil {
+			return fmt.Errorf("level override of %s: %v", pair[:i], err)
+		}
+		if lv == InvalidLevel {
+			return fmt.Errorf("level override of %s: missing level", pair[:i])
+		}
+		levels[strings.TrimSpace(pair[:i])] = lv
+	}
+
+	for name, lv := range levels {
+		if err := SetLevelFor(name, lv); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// effectiveLevel returns override of l.name (see SetLevelFor) or level of l.
+func (l *logger) effectiveLevel() Level {
+	if l.name != "" && atomic.LoadInt32(&subsystems.count) > 0 {
+		if lv, ok := levelFor(l.name); ok {
+			return lv
+		}
+	}
+	return l.level.get()
+}
+
+// levelFor returns override of name or of its closest parent.
+func levelFor(name string) (Level, bool) {
+	subsystems.mu.RLock()
+	defer subsystems.mu.RUnlock()
+
+	for {
+		if lv, ok := subsystems.levels[name]; ok {
+			return lv, true
+		}
+		i := strings.LastIndexByte(name, '.')
+		if i < 0 {
+			return InvalidLevel, false
+		}
+		name = name[:i]
+	}
+}
@@ -0,0 +1,74 @@
+package clog
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestSetLevelFor(t *testing.T) {
+	defer func() {
+		for _, name := range []string{"db", "db.conn", "http"} {
+			SetLevelFor(name, InvalidLevel)
+		}
+	}()
+
+	buf := &bytes.Buffer{}
+	root, err := New(buf, "warning", false, WithClock(testNow), WithConsole(false))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	db := root.Named("db")
+	conn := db.Named("conn")
+	http := root.Named("http")
+	cache := root.Named("cache")
+
+	if err := SetLevelsFromString("db=debug, db.conn=error,http=info"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		l        Logger
+		expected Level
+	}{
+		{"root", root, WarnLevel},
+		{"db", db, DebugLevel},
+		{"db.conn", conn, ErrorLevel}, // the most specific name wins
+		{"db.conn.pool", conn.Named("pool"), ErrorLevel},
+		{"http", http, InfoLevel},
+		{"cache", cache, WarnLevel}, // fallback to base level
+	}
+	for _, tt := range tests {
+		for lv := ErrorLevel; lv <= DebugLevel; lv++ {
+			if got, expected := tt.l.IsEnabled(lv), lv <= tt.expected; got != expected {
+				t.Errorf("%s: IsEnabled(%v) expected: %v, got: %v", tt.name, lv, expected, got)
+			}
+		}
+	}
+
+	http.Info("shown")
+	cache.Info("hidden")
+	if expected := "INFO:  2017/03/09 14:05:07 http: shown\n"; buf.String() != expected {
+		t.Errorf("expected: %q, got: %q", expected, buf.String())
+	}
+
+	// removed override falls back to base level
+	SetLevelFor("http", InvalidLevel)
+	if http.IsEnabled(InfoLevel) {
+		t.Error("http: expected base level after override removal")
+	}
+}
+
+func TestSetLevelsFromStringInvalid(t *testing.T) {
+	for _, s := range []string{"db", "=debug", "db=verbose", "db="} {
+		if err := SetLevelsFromString("ok=info," + s); err == nil {
+			t.Errorf("%q: expected error, got nil", s)
+		}
+	}
+	if _, ok := levelFor("ok"); ok {
+		t.Error("no override should be set for invalid input")
+	}
+	if err := SetLevelFor("db", Level(999)); err == nil {
+		t.Error("invalid level: expected error, got nil")
+	}
+}
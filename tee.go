@@ -0,0 +1,481 @@
+//
+// Copyright 2017 Radovan Vrždiak. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package clog
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"os"
+)
+
+// Tee returns Logger forwarding every call to each of loggers, e.g. JSON logger for a collector
+// and text logger for a file. Level handling is up to each logger. Nil loggers are skipped.
+//
+// Fatal methods write the message to all loggers first and then exit only once,
+// using exit code and FatalPolicy of the first logger created by this package.
+// Loggers implemented outside of this package receive fatal messages as errors and they are synced.
+// Queued messages of AsyncLogger are written before the fatal message.
+//
+// Caller info of debug messages points to Tee itself.
+func Tee(loggers ...Logger) Logger {
+	t := make(tee, 0, len(loggers))
+	for _, l := range loggers {
+		if l != nil {
+			t = append(t, l)
+		}
+	}
+	return t
+}
+
+type tee []Logger
+
+// internal returns implementation of l if it is logger of this package, nil otherwise.
+func internal(l Logger) *logger {
+	switch x := l.(type) {
+	case *logger:
+		return x
+	case disabled:
+		return x.logger
+	}
+	return nil
+}
+
+func (t tee) Fatal(msg ...interface{}) {
+	t.fatal(func(l *logger) entry { return l.compose(msg...) }, func(l Logger) { l.Error(msg...) })
+}
+
+func (t tee) Fatalf(format string, msg ...interface{}) {
+	t.fatal(func(l *logger) entry { return l.composef(format, msg...) },
+		func(l Logger) { l.Errorf(format, msg...) })
+}
+
+func (t tee) FatalContext(ctx context.Context, msg ...interface{}) {
+	t.fatal(func(l *logger) entry { return l.withContext(ctx, l.compose(msg...)) },
+		func(l Logger) { l.ErrorContext(ctx, msg...) })
+}
+
+func (t tee) Fatalln(msg ...interface{}) {
+	t.fatal(func(l *logger) entry { return l.composeln(msg...) }, func(l Logger) { l.Errorln(msg...) })
+}
+
+func (t tee) Fatalw(msg string, fields ...Field) {
+	t.fatal(func(l *logger) entry { return l.composew(msg, fields) },
+		func(l Logger) { l.Errorw(msg, fields...) })
+}
+
+// fatal writes message to all loggers and exits.
+// compose returns message of internal logger, foreign loggers are called by errorf.
+func (t tee) fatal(compose func(*logger) entry, errorf func(Logger)) {
+	var first *logger
+	var msg string
+	var foreign []Logger
+	for _, l := range t {
+		if a, ok := l.(*AsyncLogger); ok {
+			a.q.drain()
+			l = a.inner
+		}
+		x := internal(l)
+		if x == nil {
+			errorf(l)
+			foreign = append(foreign, l)
+			continue
+		}
+		if x.fatal == nil {
+			continue // not initialized
+		}
+		e := compose(x)
+		if first == nil {
+			first, msg = x, e.msg
+		}
+		x.writeFatal(e)
+	}
+	for _, l := range foreign {
+		l.Sync()
+	}
+
+	if first == nil {
+		os.Exit(1)
+	}
+	first.afterFatal(msg)
+}
+
+func (t tee) Error(msg ...interface{}) {
+	for _, l := range t {
+		l.Error(msg...)
+	}
+}
+
+func (t tee) Errorf(format string, msg ...interface{}) {
+	for _, l := range t {
+		l.Errorf(format, msg...)
+	}
+}
+
+// Err writes the error message to all loggers and returns error of the first one.
+func (t tee) Err(format string, args ...interface{}) error {
+	var first error
+	for _, l := range t {
+		if err := l.Err(format, args...); first == nil {
+			first = err
+		}
+	}
+	if first == nil {
+		first = fmt.Errorf(format, args...)
+	}
+	return first
+}
+
+func (t tee) ErrorErr(err error, msg ...interface{}) {
+	for _, l := range t {
+		l.ErrorErr(err, msg...)
+	}
+}
+
+func (t tee) Errorw(msg string, fields ...Field) {
+	for _, l := range t {
+		l.Errorw(msg, fields...)
+	}
+}
+
+func (t tee) Warn(msg ...interface{}) {
+	for _, l := range t {
+		l.Warn(msg...)
+	}
+}
+
+func (t tee) Warnf(format string, msg ...interface{}) {
+	for _, l := range t {
+		l.Warnf(format, msg...)
+	}
+}
+
+func (t tee) Warnw(msg string, fields ...Field) {
+	for _, l := range t {
+		l.Warnw(msg, fields...)
+	}
+}
+
+func (t tee) Info(msg ...interface{}) {
+	for _, l := range t {
+		l.Info(msg...)
+	}
+}
+
+func (t tee) Infof(format string, msg ...interface{}) {
+	for _, l := range t {
+		l.Infof(format, msg...)
+	}
+}
+
+func (t tee) Infow(msg string, fields ...Field) {
+	for _, l := range t {
+		l.Infow(msg, fields...)
+	}
+}
+
+func (t tee) Debug(msg ...interface{}) {
+	for _, l := range t {
+		l.Debug(msg...)
+	}
+}
+
+func (t tee) Debugf(format string, msg ...interface{}) {
+	for _, l := range t {
+		l.Debugf(format, msg...)
+	}
+}
+
+func (t tee) Debugw(msg string, fields ...Field) {
+	for _, l := range t {
+		l.Debugw(msg, fields...)
+	}
+}
+
+func (t tee) Print(msg ...interface{}) {
+	for _, l := range t {
+		l.Print(msg...)
+	}
+}
+
+func (t tee) Printf(format string, msg ...interface{}) {
+	for _, l := range t {
+		l.Printf(format, msg...)
+	}
+}
+
+func (t tee) Println(msg ...interface{}) {
+	for _, l := range t {
+		l.Println(msg...)
+	}
+}
+
+func (t tee) Log(level Level, msg ...interface{}) {
+	for _, l := range t {
+		l.Log(level, msg...)
+	}
+}
+
+func (t tee) Logf(level Level, format string, msg ...interface{}) {
+	for _, l := range t {
+		l.Logf(level, format, msg...)
+	}
+}
+
+func (t tee) Errorln(msg ...interface{}) {
+	for _, l := range t {
+		l.Errorln(msg...)
+	}
+}
+
+func (t tee) Warnln(msg ...interface{}) {
+	for _, l := range t {
+		l.Warnln(msg...)
+	}
+}
+
+func (t tee) Infoln(msg ...interface{}) {
+	for _, l := range t {
+		l.Infoln(msg...)
+	}
+}
+
+func (t tee) DebugBytes(prefix string, b []byte) {
+	for _, l := range t {
+		l.DebugBytes(prefix, b)
+	}
+}
+
+func (t tee) Debugln(msg ...interface{}) {
+	for _, l := range t {
+		l.Debugln(msg...)
+	}
+}
+
+func (t tee) ErrorContext(ctx context.Context, msg ...interface{}) {
+	for _, l := range t {
+		l.ErrorContext(ctx, msg...)
+	}
+}
+
+func (t tee) WarnContext(ctx context.Context, msg ...interface{}) {
+	for _, l := range t {
+		l.WarnContext(ctx, msg...)
+	}
+}
+
+func (t tee) InfoContext(ctx context.Context, msg ...interface{}) {
+	for _, l := range t {
+		l.InfoContext(ctx, msg...)
+	}
+}
+
+func (t tee) DebugContext(ctx context.Context, msg ...interface{}) {
+	for _, l := range t {
+		l.DebugContext(ctx, msg...)
+	}
+}
+
+// StdLogger returns standard library logger writing to all loggers.
+func (t tee) StdLogger(level Level) *log.Logger {
+	ws := make([]io.Writer, 0, len(t))
+	for _, l := range t {
+		ws = append(ws, l.StdLogger(level).Writer())
+	}
+	return log.New(io.MultiWriter(ws...), "", 0)
+}
+
+// ForLevel returns function writing messages at given level to all loggers.
+func (t tee) ForLevel(level Level) func(msg ...interface{}) {
+	fns := make([]func(...interface{}), 0, len(t))
+	for _, l := range t {
+		fns = append(fns, l.ForLevel(level))
+	}
+	return func(msg ...interface{}) {
+		for _, fn := range fns {
+			fn(msg...)
+		}
+	}
+}
+
+// Named returns Tee of named children.
+func (t tee) Named(name string) Logger {
+	named := make(tee, 0, len(t))
+	for _, l := range t {
+		named = append(named, l.Named(name))
+	}
+	return named
+}
+
+// Clone returns Tee of clones.
+func (t tee) Clone() Logger {
+	clones := make(tee, 0, len(t))
+	for _, l := range t {
+		clones = append(clones, l.Clone())
+	}
+	return clones
+}
+
+// WithPrefix returns Tee of children with prefix.
+func (t tee) WithPrefix(prefix string) Logger {
+	children := make(tee, 0, len(t))
+	for _, l := range t {
+		children = append(children, l.WithPrefix(prefix))
+	}
+	return children
+}
+
+// WithFields returns Tee of children with fields.
+func (t tee) WithFields(fields map[string]interface{}) Logger {
+	children := make(tee, 0, len(t))
+	for _, l := range t {
+		children = append(children, l.WithFields(fields))
+	}
+	return children
+}
+
+func (t tee) WriteRaw(level Level, p []byte) {
+	for _, l := range t {
+		l.WriteRaw(level, p)
+	}
+}
+
+// IsEnabled reports whether any of loggers writes messages of level.
+func (t tee) IsEnabled(level Level) bool {
+	for _, l := range t {
+		if l.IsEnabled(level) {
+			return true
+		}
+	}
+	return false
+}
+
+// ActiveLevels returns levels active in any of loggers.
+func (t tee) ActiveLevels() []Level {
+	active := make(map[Level]bool)
+	for _, l := range t {
+		for _, lv := range l.ActiveLevels() {
+			active[lv] = true
+		}
+	}
+
+	var levels []Level
+	for _, lv := range AllLevels() {
+		if active[lv] {
+			levels = append(levels, lv)
+		}
+	}
+	return levels
+}
+
+// Counts returns sums of counts of all loggers.
+func (t tee) Counts() map[Level]uint64 {
+	counts := make(map[Level]uint64, DebugLevel)
+	for _, l := range t {
+		for lv, n := range l.Counts() {
+			counts[lv] += n
+		}
+	}
+	return counts
+}
+
+// Close closes all loggers and returns the first error.
+func (t tee) Close() error {
+	var first error
+	for _, l := range t {
+		if err := l.Close(); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
+
+// Sync syncs all loggers and returns the first error.
+func (t tee) Sync() error {
+	var first error
+	for _, l := range t {
+		if err := l.Sync(); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
+
+func (t tee) SetFields(fields ...Field) {
+	for _, l := range t {
+		l.SetFields(fields...)
+	}
+}
+
+func (t tee) ClearFields() {
+	for _, l := range t {
+		l.ClearFields()
+	}
+}
+
+// Truncate truncates storage files of all loggers and returns the first error.
+func (t tee) Truncate() error {
+	var first error
+	for _, l := range t {
+		if err := l.Truncate(); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
+
+// SetOutput sets the same storage writer to all loggers.
+func (t tee) SetOutput(w io.Writer) {
+	for _, l := range t {
+		l.SetOutput(w)
+	}
+}
+
+// SetLevel sets level of all loggers and returns the first error.
+func (t tee) SetLevel(level Level) error {
+	var first error
+	for _, l := range t {
+		if err := l.SetLevel(level); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
+
+// EnableLevel enables level in all loggers.
+func (t tee) EnableLevel(level Level) {
+	for _, l := range t {
+		l.EnableLevel(level)
+	}
+}
+
+// DisableLevel disables level in all loggers.
+func (t tee) DisableLevel(level Level) {
+	for _, l := range t {
+		l.DisableLevel(level)
+	}
+}
+
+// Reset resets all loggers.
+func (t tee) Reset() {
+	for _, l := range t {
+		l.Reset()
+	}
+}
+
+// WithLevel sets level of all loggers, restore restores all of them.
+func (t tee) WithLevel(level Level) (restore func()) {
+	restores := make([]func(), 0, len(t))
+	for _, l := range t {
+		restores = append(restores, l.WithLevel(level))
+	}
+	return func() {
+		for _, r := range restores {
+			r()
+		}
+	}
+}
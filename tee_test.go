@@ -0,0 +1,91 @@
+package clog
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestTee(t *testing.T) {
+	first, second := &bytes.Buffer{}, &bytes.Buffer{}
+	l1, err := New(first, "debug", false, WithCallerPath(ShortPath))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	l2, err := New(second, "warning", false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	l := Tee(l1, nil, l2)
+	l.Debug("debug")
+	l.Infof("info %d", 1)
+	l.Warn("warn")
+	l.Named("sub").Warn("named")
+	l.StdLogger(WarnLevel).Print("std")
+
+	for _, s := range []string{"DEBUG: ", "INFO:  ", "info 1", "warn", "sub: named", "std"} {
+		if !strings.Contains(first.String(), s) {
+			t.Errorf("first logger: expected %q, got: %q", s, first.String())
+		}
+	}
+	for _, s := range []string{"warn", "sub: named", "std"} {
+		if !strings.Contains(second.String(), s) {
+			t.Errorf("second logger: expected %q, got: %q", s, second.String())
+		}
+	}
+	for _, s := range []string{"DEBUG: ", "INFO:  "} {
+		if strings.Contains(second.String(), s) {
+			t.Errorf("second logger: unexpected %q in %q", s, second.String())
+		}
+	}
+
+	if !l.IsEnabled(DebugLevel) || l.IsEnabled(Level(999)) {
+		t.Error("IsEnabled should report whether any logger is enabled")
+	}
+}
+
+func TestTeeFatal(t *testing.T) {
+	first, second := &bytes.Buffer{}, &bytes.Buffer{}
+	l1, _ := New(first, "info", false, WithExitCode(4))
+	l2, _ := New(second, "disabled", false, WithExitCode(5))
+
+	var codes []int
+	internal(l1).exit = func(code int) { codes = append(codes, code) }
+	internal(l2).exit = func(code int) { codes = append(codes, code) }
+
+	Tee(l1, l2).Fatalf("bye %s", "all")
+
+	if len(codes) != 1 || codes[0] != 4 {
+		t.Errorf("expected single exit with code 4, got: %v", codes)
+	}
+	for i, buf := range []*bytes.Buffer{first, second} {
+		if !strings.Contains(buf.String(), "FATAL: ") || !strings.Contains(buf.String(), "bye all") {
+			t.Errorf("logger %d: expected fatal message, got: %q", i, buf.String())
+		}
+	}
+}
+
+func TestTeeFatalAsync(t *testing.T) {
+	var codes []int
+	inner, buf := NewTestLogger(InfoLevel, WithFlags(0), WithExitFunc(func(code int) { codes = append(codes, code) }))
+	other := &bytes.Buffer{}
+	l2, _ := New(other, "info", false, WithFlags(0), WithConsole(false),
+		WithExitFunc(func(code int) { codes = append(codes, code) }))
+
+	a := NewAsync(inner, 16, QueueBlock)
+	l := Tee(a, l2)
+	l.Info("one")
+	l.Info("two")
+	l.Fatal("bye")
+
+	if len(codes) != 1 || codes[0] != 1 {
+		t.Errorf("expected single exit with code 1, got: %v", codes)
+	}
+	if expected := "INFO:  one\nINFO:  two\nFATAL: bye\n"; buf.String() != expected {
+		t.Errorf("expected: %q, got: %q", expected, buf.String())
+	}
+	if expected := "INFO:  one\nINFO:  two\nFATAL: bye\n"; other.String() != expected {
+		t.Errorf("expected: %q, got: %q", expected, other.String())
+	}
+}
@@ -0,0 +1,40 @@
+//
+// Copyright 2017 Radovan Vrždiak. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package clog
+
+import (
+	"bytes"
+	"io"
+	"sync"
+)
+
+// NewTestLogger creates Logger writing only to the returned buffer, without console output.
+// It is intended for tests of code which logs, e.g. to assert that an error was logged.
+// Writes to the buffer are serialized, the buffer should be read after logging is done.
+// It panics if level is not valid (see Level.Validate).
+func NewTestLogger(level Level, opts ...Option) (Logger, *bytes.Buffer) {
+	buf := &bytes.Buffer{}
+	opts = append([]Option{WithConsole(false)}, opts...)
+	l, err := NewWithLevel(&lockedWriter{w: buf}, level, false, opts...)
+	if err != nil {
+		panic("clog: " + err.Error())
+	}
+
+	return l, buf
+}
+
+// lockedWriter serializes writes of outputs of all levels to w.
+type lockedWriter struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func (lw *lockedWriter) Write(p []byte) (int, error) {
+	lw.mu.Lock()
+	defer lw.mu.Unlock()
+
+	return lw.w.Write(p)
+}
@@ -0,0 +1,50 @@
+package clog
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func ExampleNewTestLogger() {
+	l, buf := NewTestLogger(InfoLevel)
+
+	l.Info("connecting")
+	l.Error("connection refused")
+
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		if strings.HasPrefix(line, "ERROR: ") && strings.HasSuffix(line, " connection refused") {
+			fmt.Println("error logged")
+		}
+	}
+	// Output: error logged
+}
+
+func TestNewTestLogger(t *testing.T) {
+	l, buf := NewTestLogger(DebugLevel, WithClock(testNow), WithCallerPath(ShortPath))
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			l.Info("i")
+			l.Error("e")
+		}()
+	}
+	wg.Wait()
+
+	if n := strings.Count(buf.String(), "\n"); n != 20 {
+		t.Errorf("expected 20 lines, got %d:\n%s", n, buf.String())
+	}
+}
+
+func TestNewTestLoggerInvalid(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("expected panic for invalid level")
+		}
+	}()
+	NewTestLogger(InvalidLevel)
+}